@@ -4,16 +4,22 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/daemtri/di"
 	"github.com/daemtri/di/box/flagx"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slog"
 )
 
 var (
 	defaultRegistrar = di.GetRegistry()
-	nfs              = flagx.NewNamedFlagSets()
+	nfs              = newNamedFlagSets()
 	nfsIsParsed      bool
-	envPrefix        = "GF"
+	// configMux 保证配置变更是串行的
+	configMux sync.Mutex
+	envPrefix = "GF"
 )
 
 // Default 返回默认di.Registry
@@ -30,42 +36,293 @@ func FlagSet(name ...string) *flag.FlagSet {
 }
 
 // Retrofiter 定义了一个可以重新构建对象的接口
+// 当对象绑定的参数发生变更时触发
 type Retrofiter interface {
 	Retrofit() error
 }
 
-// Retrofit 遍历reg种所有已经构建完成的对象
-// 如果builder实现了Retrofit，则触发一次Retrofit
-func retrofit() error {
-	var err error
+// Change 描述了一个参数的变更
+type Change struct {
+	Key    string
+	Old    string
+	New    string
+	Source flagx.Source
+}
+
+// ChangeRetrofiter 定义了一个可以根据参数变更重新构建对象的接口
+// 当对象绑定的参数发生变更时触发，changes为该对象发生变更的参数
+type ChangeRetrofiter interface {
+	RetrofitChanges(changes []Change) error
+}
+
+// unwrapper 由包装了用户builder的内部builder实现，返回被包装的对象
+type unwrapper interface {
+	unwrap() any
+}
+
+// retrofitTargets 返回builder本身以及被其包装的对象
+func retrofitTargets(builder any) []any {
+	targets := []any{builder}
+	for {
+		u, ok := builder.(unwrapper)
+		if !ok {
+			return targets
+		}
+		builder = u.unwrap()
+		if builder == nil {
+			return targets
+		}
+		targets = append(targets, builder)
+	}
+}
+
+// retrofit 对参数发生变更的已构建对象触发Retrofit，返回触发了Retrofit的对象
+func retrofit(diffs []di.ComponentDiff, source flagx.Source, skip map[string]bool) ([]string, error) {
+	values := make(map[string]di.Value)
 	defaultRegistrar.Visit(func(v di.Value) {
-		if v.Instance() != nil {
-			if r, ok := v.Builder().(Retrofiter); ok {
-				err2 := r.Retrofit()
-				if err2 != nil {
-					err = errors.Join(err, err2)
-				}
+		values[fmt.Sprintf("%s(%s)", v.Type, v.Name)] = v
+	})
+	var err error
+	var retrofitted []string
+	for _, diff := range diffs {
+		v, ok := values[diff.String()]
+		if !ok || !v.Built() || len(diff.Flags) == 0 || skip[diff.String()] {
+			continue
+		}
+		changes := make([]Change, 0, len(diff.Flags))
+		for _, fd := range diff.Flags {
+			changes = append(changes, Change{Key: fd.Key, Old: fd.Old, New: fd.New, Source: source})
+		}
+		for _, target := range retrofitTargets(v.Builder()) {
+			var err2 error
+			if r, ok := target.(ChangeRetrofiter); ok {
+				err2 = r.RetrofitChanges(changes)
+			} else if r, ok := target.(Retrofiter); ok {
+				err2 = r.Retrofit()
+			} else {
+				continue
+			}
+			retrofitted = append(retrofitted, diff.String())
+			if err2 != nil {
+				err = errors.Join(err, fmt.Errorf("retrofit %s failed: %w", diff, err2))
 			}
+			break
 		}
-	})
-	return err
+	}
+	return retrofitted, err
+}
+
+// Snapshot 返回当前所有对象的构建状态及参数值，敏感参数的值会被脱敏
+func Snapshot() di.Snapshot {
+	s := defaultRegistrar.Snapshot()
+	for i := range s.Components {
+		for key := range s.Components[i].Flags {
+			s.Components[i].Flags[key] = nfs.Redact(key, s.Components[i].Flags[key])
+		}
+	}
+	return s
+}
+
+// RejectedItem 描述了一个被拒绝的配置项
+type RejectedItem struct {
+	Key    string
+	Value  string
+	Source flagx.Source
+	Reason error
+}
+
+// ConfigRejectedError 配置变更被拒绝，本次所有的配置变更都已经回滚
+type ConfigRejectedError struct {
+	Source flagx.Source
+	// Items 为设置失败的配置项
+	Items []RejectedItem
+	// Validation 为配置设置成功后，校验失败的错误
+	Validation error
+}
+
+func (e *ConfigRejectedError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "配置变更被拒绝: source=%s", e.Source)
+	for _, item := range e.Items {
+		fmt.Fprintf(&sb, "; key=%s,value=%s,source=%s,error=%s", item.Key, nfs.Redact(item.Key, item.Value), item.Source, item.Reason)
+	}
+	if e.Validation != nil {
+		fmt.Fprintf(&sb, "; validate error: %s", e.Validation)
+	}
+	return sb.String()
+}
+
+func (e *ConfigRejectedError) Unwrap() error {
+	return e.Validation
 }
 
 // SetConfig 设置配置
+// 所有配置项设置成功并且受影响的对象校验通过后才会生效，否则回滚所有变更并返回 *ConfigRejectedError，
+// 不存在的参数会被忽略并返回 *UnknownKeysError，已经被更高优先级来源设置的参数会被忽略
 func SetConfig(items []ConfigItem, source flagx.Source) error {
+	unknown, err := applyConfig([]configBatch{{items: items, source: source}}, source)
+	return errors.Join(err, unknownKeysError(unknown))
+}
+
+// SetConfigLayers 分层设置配置，layers中越靠后的层优先级越高，每一层都会作为source中独立的一层来源被记录，
+// 所有层的配置项作为一个整体生效或者回滚，其他规则与SetConfig一致
+func SetConfigLayers(layers []ConfigLayer, source flagx.Source) error {
+	unknown, err := applyConfig(layerBatches(layers, source), source)
+	return errors.Join(err, unknownKeysError(unknown))
+}
+
+func layerBatches(layers []ConfigLayer, source flagx.Source) []configBatch {
+	batches := make([]configBatch, 0, len(layers))
+	for i := range layers {
+		batches = append(batches, configBatch{
+			items:   layers[i].Items,
+			removed: layers[i].Removed,
+			source:  flagx.NewLayerSource(source, layers[i].Name, i+1),
+		})
+	}
+	return batches
+}
+
+// applyConfig 设置配置并通知订阅者，单独返回不存在对应参数的key
+func applyConfig(batches []configBatch, source flagx.Source) ([]UnknownKey, error) {
+	changes, unknown, err := setConfig(batches, source)
+	notifyConfigChange(changes)
+	return unknown, err
+}
+
+// configBatch 一批来自同一来源的配置项，removed为该来源删除的配置项的key
+type configBatch struct {
+	items   []ConfigItem
+	removed []string
+	source  flagx.Source
+}
+
+func setConfig(batches []configBatch, source flagx.Source) ([]Change, []UnknownKey, error) {
+	configMux.Lock()
+	defer configMux.Unlock()
+
+	// 将别名替换为参数的完整名称，之后都使用完整名称记录变更
+	for i := range batches {
+		items := make([]ConfigItem, len(batches[i].items))
+		for j, item := range batches[i].items {
+			items[j] = ConfigItem{Key: nfs.ResolveAlias(item.Key), Value: item.Value}
+		}
+		batches[i].items = items
+		removed := make([]string, len(batches[i].removed))
+		for j, key := range batches[i].removed {
+			removed[j] = nfs.ResolveAlias(key)
+		}
+		batches[i].removed = removed
+	}
+
+	before := defaultRegistrar.Snapshot()
+	oldValues := make(map[string]string)
+	keys := make([]string, 0)
+	for _, batch := range batches {
+		batchKeys := make([]string, 0, len(batch.items)+len(batch.removed))
+		for _, item := range batch.items {
+			batchKeys = append(batchKeys, item.Key)
+		}
+		for _, key := range append(batchKeys, batch.removed...) {
+			if _, ok := oldValues[key]; ok {
+				continue
+			}
+			if f := nfs.Lookup(key); f != nil {
+				oldValues[key] = f.Value.String()
+				keys = append(keys, key)
+			}
+		}
+	}
+	tx := nfs.Begin()
 	var errs error
-	for _, item := range items {
-		if err := nfs.Set(item.Key, item.Value, source); err != nil {
-			errs = errors.Join(errs, fmt.Errorf("配置变更失败: key=%s,value=%s,error=%s", item.Key, item.Value, err))
+	var rejected []RejectedItem
+	var unknown []UnknownKey
+	for _, batch := range batches {
+		for _, item := range batch.items {
+			value := item.Value
+			if nfs.IsSecret(item.Key) && nfs.CanSet(item.Key, batch.source) {
+				resolved, err := resolveSecret(value)
+				if err != nil {
+					rejected = append(rejected, RejectedItem{Key: item.Key, Value: item.Value, Source: batch.source, Reason: err})
+					continue
+				}
+				value = resolved
+			}
+			err := tx.Set(item.Key, value, batch.source)
+			switch {
+			case err == nil, errors.Is(err, flagx.ErrShadowed):
+			case errors.Is(err, flagx.ErrUnknownKey):
+				unknown = append(unknown, UnknownKey{Key: item.Key, Source: batch.source, Suggestion: suggestKey(item.Key)})
+			default:
+				rejected = append(rejected, RejectedItem{Key: item.Key, Value: item.Value, Source: batch.source, Reason: err})
+			}
+		}
+		// 删除不存在的参数时没有任何影响，不需要报告为未知的key
+		for _, key := range batch.removed {
+			if err := tx.Unset(key, batch.source); err != nil && !errors.Is(err, flagx.ErrUnknownKey) {
+				rejected = append(rejected, RejectedItem{Key: key, Source: batch.source, Reason: err})
+			}
 		}
 	}
-	err2 := defaultRegistrar.ValidateFlags()
-	if err2 != nil {
-		errs = errors.Join(errs, err2)
+	diffs := di.Diff(before, defaultRegistrar.Snapshot())
+	var validation error
+	if len(rejected) == 0 {
+		validation = validateComponents(diffs)
 	}
-	err3 := retrofit()
+	if len(rejected) > 0 || validation != nil {
+		if err := tx.Rollback(); err != nil {
+			slog.Error("config rollback failed", "source", source, "error", err)
+		}
+		return nil, unknown, &ConfigRejectedError{Source: source, Items: rejected, Validation: validation}
+	}
+
+	changes := make([]Change, 0, len(keys))
+	for _, key := range keys {
+		pv, _ := nfs.Provenance(key)
+		if pv.Value != oldValues[key] {
+			changes = append(changes, Change{Key: key, Old: oldValues[key], New: pv.Value, Source: pv.Source})
+		}
+	}
+
+	for _, diff := range diffs {
+		for _, fd := range diff.Flags {
+			slog.Info("config changed", "source", source, "component", diff,
+				"key", fd.Key, "old", nfs.Redact(fd.Key, fd.Old), "new", nfs.Redact(fd.Key, fd.New))
+		}
+	}
+	reloaded, err3 := reload(diffs)
 	if err3 != nil {
 		errs = errors.Join(errs, err3)
 	}
+	if len(reloaded) > 0 {
+		slog.Info("components reloaded", "source", source, "components", maps.Keys(reloaded))
+	}
+	retrofitted, err4 := retrofit(diffs, source, reloaded)
+	if err4 != nil {
+		errs = errors.Join(errs, err4)
+	}
+	if len(retrofitted) > 0 {
+		slog.Info("components retrofitted", "source", source, "components", retrofitted)
+	}
+	return changes, unknown, errs
+}
+
+// validateComponents 校验参数发生变更的对象
+func validateComponents(diffs []di.ComponentDiff) error {
+	changed := make(map[string]bool, len(diffs))
+	for _, diff := range diffs {
+		if len(diff.Flags) > 0 {
+			changed[diff.String()] = true
+		}
+	}
+	var errs error
+	defaultRegistrar.Visit(func(v di.Value) {
+		if !changed[fmt.Sprintf("%s(%s)", v.Type, v.Name)] {
+			return
+		}
+		if err := v.ValidateFlags(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s(%s): %w", v.Type, v.Name, err))
+		}
+	})
 	return errs
 }
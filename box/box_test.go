@@ -0,0 +1,313 @@
+package box
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/daemtri/di"
+	"github.com/daemtri/di/box/flagx"
+)
+
+// resetFlags 使用新的参数集合替换全局参数集合，测试结束后恢复，
+// 容器是全局的，所以每个测试需要使用各自独立的类型
+func resetFlags(t *testing.T) {
+	t.Helper()
+	oldNfs, oldParsed := nfs, nfsIsParsed
+	nfs = newNamedFlagSets()
+	nfsIsParsed = false
+	t.Cleanup(func() {
+		nfs, nfsIsParsed = oldNfs, oldParsed
+	})
+}
+
+// bindFlags 与Build一样绑定所有参数，并使用args作为命令行参数解析，返回绑定了所有参数的flagSet
+func bindFlags(t *testing.T, args ...string) *flag.FlagSet {
+	t.Helper()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+	}()
+	os.Args = append([]string{oldArgs[0]}, args...)
+	fs := flag.NewFlagSet(oldArgs[0], flag.ContinueOnError)
+	nfs.BindFlagSet(fs, envPrefix)
+	return fs
+}
+
+// testSource 来源的名称是全局唯一的，使用测试名称作为来源名称
+func testSource(t *testing.T, name ...string) flagx.Source {
+	t.Helper()
+	return flagx.NewSource(strings.Join(append([]string{t.Name()}, name...), ":"))
+}
+
+type txTestServer[T any] struct {
+	Host   string            `flag:"host" default:"localhost"`
+	Port   int               `flag:"port" default:"80" validate:"gt=0"`
+	Tags   []string          `flag:"tags" default:"a,b"`
+	Labels map[string]string `flag:"labels" default:"k=v"`
+}
+
+func (s *txTestServer[T]) Build(ctx context.Context) (*txTestServer[T], error) {
+	return s, nil
+}
+
+func (s *txTestServer[T]) assertDefaults(t *testing.T) {
+	t.Helper()
+	want := &txTestServer[T]{Host: "localhost", Port: 80, Tags: []string{"a", "b"}, Labels: map[string]string{"k": "v"}}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("values not restored: %+v", s)
+	}
+}
+
+type (
+	txRejected   struct{}
+	txValidation struct{}
+	txShadowed   struct{}
+	txRemoved    struct{}
+)
+
+func TestSetConfigRejectedItem(t *testing.T) {
+	resetFlags(t)
+	server := &txTestServer[txRejected]{}
+	Provide[*txTestServer[txRejected]](server, WithFlags("tx"))
+	bindFlags(t)
+
+	source := testSource(t)
+	err := SetConfig([]ConfigItem{
+		{Key: "tx-host", Value: "example.com"},
+		{Key: "tx-port", Value: "not-a-number"},
+	}, source)
+	var rejected *ConfigRejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("SetConfig error = %v, want *ConfigRejectedError", err)
+	}
+	if rejected.Source != source || len(rejected.Items) != 1 || rejected.Items[0].Key != "tx-port" || rejected.Validation != nil {
+		t.Errorf("unexpected rejected error: %+v", rejected)
+	}
+	server.assertDefaults(t)
+}
+
+func TestSetConfigValidationFailed(t *testing.T) {
+	resetFlags(t)
+	server := &txTestServer[txValidation]{}
+	Provide[*txTestServer[txValidation]](server, WithFlags("tx"))
+	bindFlags(t)
+
+	err := SetConfigLayers([]ConfigLayer{
+		{Name: "base.yaml", Items: []ConfigItem{
+			{Key: "tx-host", Value: "base"},
+			{Key: "tx-tags", Value: "[c,d]"},
+			{Key: "tx-labels", Value: "x=y"},
+		}},
+		{Name: "prod.yaml", Items: []ConfigItem{
+			{Key: "tx-host", Value: "prod"},
+			{Key: "tx-port", Value: "0"},
+		}},
+	}, testSource(t))
+	var rejected *ConfigRejectedError
+	if !errors.As(err, &rejected) || rejected.Validation == nil || len(rejected.Items) != 0 {
+		t.Fatalf("SetConfigLayers error = %v, want validation error", err)
+	}
+	server.assertDefaults(t)
+	for _, key := range []string{"tx-host", "tx-port", "tx-tags", "tx-labels"} {
+		pv, _ := nfs.Provenance(key)
+		if pv.Source != nil || len(pv.Shadowed) > 0 {
+			t.Errorf("%s provenance not restored: %+v", key, pv)
+		}
+	}
+}
+
+func TestSetConfigShadowed(t *testing.T) {
+	resetFlags(t)
+	server := &txTestServer[txShadowed]{}
+	Provide[*txTestServer[txShadowed]](server, WithFlags("tx"))
+	bindFlags(t, "--tx-host=cli")
+
+	source := testSource(t)
+	if err := SetConfig([]ConfigItem{{Key: "tx-host", Value: "file"}}, source); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	if server.Host != "cli" {
+		t.Errorf("shadowed key changed: %s", server.Host)
+	}
+	err := SetConfig([]ConfigItem{
+		{Key: "tx-host", Value: "file2"},
+		{Key: "tx-port", Value: "-1"},
+	}, source)
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	pv, _ := nfs.Provenance("tx-host")
+	if server.Host != "cli" || len(pv.Shadowed) != 1 || pv.Shadowed[0].Value != "file" {
+		t.Errorf("shadowed key changed by rollback: host=%s, provenance=%+v", server.Host, pv)
+	}
+	if server.Port != 80 {
+		t.Errorf("port not restored: %d", server.Port)
+	}
+}
+
+// updatingTestLoader 依次报告updates中的每一次更新
+type updatingTestLoader struct {
+	updates []ConfigUpdate
+}
+
+func (l *updatingTestLoader) Load(ctx context.Context, set func([]ConfigItem)) error {
+	return l.LoadUpdates(ctx, func(update ConfigUpdate) { set(update.Items) })
+}
+
+func (l *updatingTestLoader) LoadUpdates(ctx context.Context, set func(update ConfigUpdate)) error {
+	for _, update := range l.updates {
+		set(update)
+	}
+	return nil
+}
+
+func TestLoadConfigRemoved(t *testing.T) {
+	resetFlags(t)
+	server := &txTestServer[txRemoved]{}
+	Provide[*txTestServer[txRemoved]](server, WithFlags("tx"))
+	bindFlags(t)
+
+	// 先创建的来源优先级更高
+	high, low := testSource(t, "high"), testSource(t, "low")
+	if err := SetConfig([]ConfigItem{{Key: "tx-port", Value: "8080"}}, low); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	var changes []Change
+	cancel := OnConfigChange("tx", func(c []Change) { changes = append(changes, c...) })
+	defer cancel()
+
+	loader := &configLoaderBuilder{
+		ConfigLoader: &updatingTestLoader{updates: []ConfigUpdate{
+			{Items: []ConfigItem{{Key: "tx_host", Value: "file"}, {Key: "tx_port", Value: "9090"}}},
+			{Removed: []string{"tx_host", "tx_port", "tx_missing"}},
+		}},
+		source:    high,
+		keyMapper: NormalizeKey,
+	}
+	if _, err := loadConfig(context.Background(), loader); err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if server.Host != "localhost" || server.Port != 8080 {
+		t.Errorf("removed keys not restored: host=%s, port=%d", server.Host, server.Port)
+	}
+	pv, _ := nfs.Provenance("tx-port")
+	if pv.Source != low {
+		t.Errorf("tx-port should fall back to %s: %+v", low, pv)
+	}
+	if len(changes) != 4 {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+}
+
+// retrofitTestServer Build返回builder本身，被validateAbleBuilder包装，通过unwrap触发RetrofitChanges
+type retrofitTestServer[T any] struct {
+	Host    string `flag:"host" default:"localhost"`
+	Port    int    `flag:"port" default:"80"`
+	changes []Change
+}
+
+func (s *retrofitTestServer[T]) Build(ctx context.Context) (*retrofitTestServer[T], error) {
+	return s, nil
+}
+
+func (s *retrofitTestServer[T]) RetrofitChanges(changes []Change) error {
+	s.changes = append(s.changes, changes...)
+	return nil
+}
+
+// retrofitTestOption 函数builder的参数结构体，通过unwrap触发Retrofit
+type retrofitTestOption struct {
+	Addr      string `flag:"addr" default:"a"`
+	retrofits int
+}
+
+func (o *retrofitTestOption) Retrofit() error {
+	o.retrofits++
+	return nil
+}
+
+type (
+	retrofitChanged   struct{}
+	retrofitUnchanged struct{}
+	retrofitTestFunc  struct{}
+)
+
+// retrofitReloaded 非空结构体，保证重新构建后的对象地址不同
+type retrofitReloaded struct {
+	addr string
+}
+
+func TestRetrofit(t *testing.T) {
+	resetFlags(t)
+	changed, unchanged := &retrofitTestServer[retrofitChanged]{}, &retrofitTestServer[retrofitUnchanged]{}
+	Provide[*retrofitTestServer[retrofitChanged]](changed, WithFlags("retrofit-changed"))
+	Provide[*retrofitTestServer[retrofitUnchanged]](unchanged, WithFlags("retrofit-unchanged"))
+	opt := &retrofitTestOption{}
+	Provide[*retrofitTestFunc](func(o *retrofitTestOption) (*retrofitTestFunc, error) {
+		opt = o
+		return &retrofitTestFunc{}, nil
+	}, WithFlags("retrofit-func"))
+	bindFlags(t)
+
+	if _, err := di.Build[*retrofitTestServer[retrofitChanged]](context.Background()); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if _, err := di.Build[*retrofitTestServer[retrofitUnchanged]](context.Background()); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if _, err := di.Build[*retrofitTestFunc](context.Background()); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	source := testSource(t)
+	if err := SetConfig([]ConfigItem{
+		{Key: "retrofit-changed-host", Value: "example.com"},
+		{Key: "retrofit-changed-port", Value: "80"},
+		{Key: "retrofit-func-addr", Value: "b"},
+	}, source); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	// 值未变化的参数不属于变更
+	want := []Change{{Key: "retrofit-changed-host", Old: "localhost", New: "example.com", Source: source}}
+	if !reflect.DeepEqual(changed.changes, want) {
+		t.Errorf("RetrofitChanges got %+v, want %+v", changed.changes, want)
+	}
+	if len(unchanged.changes) != 0 {
+		t.Errorf("component without changed flags retrofitted: %+v", unchanged.changes)
+	}
+	if opt.retrofits != 1 || opt.Addr != "b" {
+		t.Errorf("wrapped option retrofitted %d times, addr=%s", opt.retrofits, opt.Addr)
+	}
+}
+
+func TestRetrofitSkipReloaded(t *testing.T) {
+	resetFlags(t)
+	setReloadDrain(t, 0)
+	opt := &retrofitTestOption{}
+	Provide[*retrofitReloaded](func(o *retrofitTestOption) (*retrofitReloaded, error) {
+		opt = o
+		return &retrofitReloaded{addr: o.Addr}, nil
+	}, WithFlags("retrofit-reload"), WithReload())
+	bindFlags(t)
+
+	live, err := di.Build[*di.Live[*retrofitReloaded]](context.Background())
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	old := live.Load()
+	if err := SetConfig([]ConfigItem{{Key: "retrofit-reload-addr", Value: "b"}}, testSource(t)); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	if current := live.Load(); current == old || current.addr != "b" {
+		t.Fatalf("component not reloaded")
+	}
+	// 已经重新构建的对象不需要再触发Retrofit
+	if opt.retrofits != 0 {
+		t.Errorf("reloaded component retrofitted %d times", opt.retrofits)
+	}
+}
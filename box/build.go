@@ -20,6 +20,7 @@ type Builder[T any] interface {
 type buildOptions struct {
 	inits         []namedInitFunc
 	configLoaders []*configLoaderBuilder
+	strictConfig  bool
 }
 type BuildOption interface {
 	apply(o *buildOptions)
@@ -46,7 +47,11 @@ func UseInit(name string, fn InitFunc) BuildOption {
 // the loader is ordered by the order of the UseConfigLoader call and
 // the earlier added loader has a higher priority.
 // all loader will be invoked  before all init function and build function
-func UseConfigLoader(name string, loader ConfigLoader) BuildOption {
+func UseConfigLoader(name string, loader ConfigLoader, opts ...LoaderOption) BuildOption {
+	lo := &loaderOptions{}
+	for i := range opts {
+		opts[i].apply(lo)
+	}
 	return buildOptionsFunc(func(o *buildOptions) {
 		if o.configLoaders == nil {
 			o.configLoaders = make([]*configLoaderBuilder, 0, 1)
@@ -59,6 +64,7 @@ func UseConfigLoader(name string, loader ConfigLoader) BuildOption {
 			ConfigLoader: loader,
 			source:       flagx.NewSource(sourceName),
 			name:         name,
+			keyMapper:    lo.keyMapper,
 		})
 	})
 }
@@ -73,6 +79,7 @@ func Build[T any](ctx context.Context, opts ...BuildOption) (T, error) {
 	for i := range opts {
 		opts[i].apply(opt)
 	}
+	buildCtx = ctx
 
 	for i := range opt.configLoaders {
 		provide[*configLoaderBuilder](opt.configLoaders[i],
@@ -82,7 +89,8 @@ func Build[T any](ctx context.Context, opts ...BuildOption) (T, error) {
 	}
 
 	Provide[*initializer[T]](&initializer[T]{
-		beforeFuncs: opt.inits,
+		beforeFuncs:  opt.inits,
+		strictConfig: opt.strictConfig,
 	}, WithOptional[*configLoaderBuilder](func(name string, err error) {
 		if err != nil {
 			slog.Warn("load config failed", "name", name, "error", err)
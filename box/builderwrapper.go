@@ -121,6 +121,10 @@ type dynamicParamsFunctionBuilder[T any] struct {
 	fnType      reflect.Type
 }
 
+func (ib *dynamicParamsFunctionBuilder[T]) unwrap() any {
+	return ib.Option
+}
+
 func (ib *dynamicParamsFunctionBuilder[T]) ValidateFlags() error {
 	if ib.Option == nil {
 		return nil
@@ -174,6 +178,68 @@ func newValidateAbleBuilder[T any](builder Builder[T]) Builder[T] {
 	}
 }
 
+func (wb *validateAbleBuilder[T]) unwrap() any {
+	return wb.Builder
+}
+
+// InjectTarget 让容器将依赖注入到被包装的builder中，而不是validateAbleBuilder本身
+func (wb *validateAbleBuilder[T]) InjectTarget() any {
+	if it, ok := wb.Builder.(interface{ InjectTarget() any }); ok {
+		return it.InjectTarget()
+	}
+	return wb.Builder
+}
+
 func (wb *validateAbleBuilder[T]) ValidateFlags() error {
 	return validate.Struct(wb.Builder)
 }
+
+// structBuilder 自动装配结构体，不需要结构体实现Build方法
+// 结构体本身即是构建结果，其inject字段会被注入依赖，flag字段会被绑定到参数
+type structBuilder[T any] struct {
+	Struct T `flag:""`
+}
+
+func newStructBuilder[T any]() Builder[T] {
+	typ := reflectType[T]()
+	if typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("ProvideStruct only supports pointer to struct, got %s", typ))
+	}
+	return &structBuilder[T]{
+		Struct: reflect.New(typ.Elem()).Interface().(T),
+	}
+}
+
+func (sb *structBuilder[T]) unwrap() any {
+	return sb.Struct
+}
+
+// InjectTarget 让容器将依赖注入到结构体中，而不是structBuilder本身
+func (sb *structBuilder[T]) InjectTarget() any {
+	return sb.Struct
+}
+
+func (sb *structBuilder[T]) ValidateFlags() error {
+	if err := validate.Struct(sb.Struct); err != nil {
+		return err
+	}
+	if v, ok := any(sb.Struct).(interface{ ValidateFlags() error }); ok {
+		return v.ValidateFlags()
+	}
+	return nil
+}
+
+// structIniter 定义了结构体在注入完成后的初始化方法
+type structIniter interface {
+	Init(ctx context.Context) error
+}
+
+// Build 调用Init并返回结构体本身，每次调用返回的都是同一个对象，并且会再次调用Init
+func (sb *structBuilder[T]) Build(ctx context.Context) (T, error) {
+	if initer, ok := any(sb.Struct).(structIniter); ok {
+		if err := initer.Init(ctx); err != nil {
+			return emptyValue[T](), err
+		}
+	}
+	return sb.Struct, nil
+}
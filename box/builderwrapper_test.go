@@ -0,0 +1,169 @@
+package box
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/daemtri/di"
+)
+
+type structTestDB struct{}
+
+type structTestService struct {
+	Addr   string        `flag:"addr" default:":8080" validate:"required"`
+	Port   int           `flag:"port" default:"80" validate:"gt=0"`
+	DB     *structTestDB `inject:"must"`
+	inited int
+}
+
+func (s *structTestService) Init(ctx context.Context) error {
+	if s.DB == nil {
+		return errors.New("DB is not injected before Init")
+	}
+	s.inited++
+	return nil
+}
+
+type structTestInvalid struct {
+	Port int `flag:"port" validate:"gt=0"`
+}
+
+type structTestReload struct {
+	Addr string `flag:"addr"`
+}
+
+func TestProvideStruct(t *testing.T) {
+	resetFlags(t)
+	Provide[*structTestDB](&structTestDB{})
+	ProvideStruct[*structTestService](WithFlags("struct-test"))
+	bindFlags(t, "--struct-test-port=8081")
+
+	svc, err := di.Build[*structTestService](context.Background())
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if svc.Addr != ":8080" || svc.Port != 8081 {
+		t.Errorf("flags not bound: addr=%s, port=%d", svc.Addr, svc.Port)
+	}
+	if svc.DB == nil {
+		t.Errorf("inject field not filled")
+	}
+	if svc.inited != 1 {
+		t.Errorf("Init called %d times, want 1", svc.inited)
+	}
+}
+
+func TestStructBuilderBuild(t *testing.T) {
+	sb := newStructBuilder[*structTestService]().(*structBuilder[*structTestService])
+	sb.Struct.DB = &structTestDB{}
+	first, err := sb.Build(context.Background())
+	if err != nil {
+		t.Fatalf("first build: %v", err)
+	}
+	second, err := sb.Build(context.Background())
+	if err != nil {
+		t.Fatalf("second build: %v", err)
+	}
+	if first != second || first != sb.Struct {
+		t.Errorf("Build should always return the struct itself")
+	}
+	if first.inited != 2 {
+		t.Errorf("Init called %d times, want 2", first.inited)
+	}
+}
+
+func TestProvideStructValidate(t *testing.T) {
+	resetFlags(t)
+	ProvideStruct[*structTestInvalid](WithFlags("struct-invalid"))
+	bindFlags(t)
+
+	if _, err := di.Build[*structTestInvalid](context.Background()); err == nil {
+		t.Errorf("expected validate error")
+	}
+}
+
+func TestProvideStructPanics(t *testing.T) {
+	tests := []struct {
+		name    string
+		provide func()
+	}{
+		{
+			name:    "not pointer to struct",
+			provide: func() { ProvideStruct[structTestReload]() },
+		},
+		{
+			name:    "with reload",
+			provide: func() { ProvideStruct[*structTestReload](WithReload()) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			tt.provide()
+		})
+	}
+}
+
+type (
+	injectBuilderDB      struct{ name string }
+	injectBuilderMissing struct{}
+)
+
+type injectBuilder struct {
+	Primary *injectBuilderDB      `inject:"must"`
+	Replica *injectBuilderDB      `inject:"name=replica"`
+	Missing *injectBuilderMissing `inject:"optional"`
+}
+
+type injectBuilderResult struct {
+	primary, replica *injectBuilderDB
+	missing          *injectBuilderMissing
+}
+
+func (b *injectBuilder) Build(ctx context.Context) (*injectBuilderResult, error) {
+	return &injectBuilderResult{primary: b.Primary, replica: b.Replica, missing: b.Missing}, nil
+}
+
+type injectInvalidBuilder struct {
+	DB *injectBuilderDB `inject:"bogus"`
+}
+
+func (b *injectInvalidBuilder) Build(ctx context.Context) (*injectInvalidBuilder, error) {
+	return b, nil
+}
+
+// TestProvideBuilderInject Provide会包装builder，依赖需要注入到被包装的builder中
+func TestProvideBuilderInject(t *testing.T) {
+	resetFlags(t)
+	Provide[*injectBuilderDB](&injectBuilderDB{name: "primary"})
+	Provide[*injectBuilderDB](&injectBuilderDB{name: "replica"}, WithName("replica"))
+	Provide[*injectBuilderResult](&injectBuilder{})
+	bindFlags(t)
+
+	result, err := di.Build[*injectBuilderResult](context.Background())
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if result.primary == nil || result.primary.name != "primary" {
+		t.Errorf("must field not injected: %+v", result.primary)
+	}
+	if result.replica == nil || result.replica.name != "replica" {
+		t.Errorf("named field not injected: %+v", result.replica)
+	}
+	if result.missing != nil {
+		t.Errorf("optional field without provider should be nil: %+v", result.missing)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Provide with invalid inject tag should panic")
+		}
+	}()
+	Provide[*injectInvalidBuilder](&injectInvalidBuilder{})
+}
@@ -2,6 +2,7 @@ package box
 
 import (
 	"context"
+	"strings"
 
 	"github.com/daemtri/di/box/flagx"
 	"github.com/daemtri/di/box/validate"
@@ -22,10 +23,101 @@ type ConfigLoader interface {
 	Load(ctx context.Context, setter func([]ConfigItem)) error
 }
 
+// ConfigUpdate 一次配置更新，Items为新增或者值发生变化的配置项，
+// Removed为已经从配置来源中删除的配置项的key，这些参数会恢复为优先级更低的来源设置的值，或者默认值
+type ConfigUpdate struct {
+	Items   []ConfigItem
+	Removed []string
+}
+
+// UpdatingConfigLoader 监听到配置变更时，可以同时报告被删除的配置项的加载器，
+// setter与ConfigLoader中的setter一样，可以在监听到配置变更时再次调用。
+// 加载器实现了UpdatingConfigLoader时只会调用LoadUpdates，内嵌其他加载器时需要同时覆盖Load和LoadUpdates
+type UpdatingConfigLoader interface {
+	ConfigLoader
+	LoadUpdates(ctx context.Context, setter func(update ConfigUpdate)) error
+}
+
+// ConfigLayer 一层配置，Name为该层的名称，如配置文件的路径
+type ConfigLayer struct {
+	Name  string
+	Items []ConfigItem
+	// Removed 为该层中已经删除的配置项的key，与ConfigUpdate中的Removed一致
+	Removed []string
+}
+
+// LayeredConfigLoader 分层加载配置的加载器，如多个配置文件，
+// 每一层都会被记录为独立的flagx.Source，layers中越靠后的层优先级越高，
+// setter与ConfigLoader中的setter一样，可以在监听到配置变更时再次调用
+type LayeredConfigLoader interface {
+	ConfigLoader
+	LoadLayers(ctx context.Context, setter func(layers []ConfigLayer)) error
+}
+
+// KeyMapper 将配置来源中的key转换为参数的完整名称
+type KeyMapper func(key string) string
+
+// NormalizeKey 将snake_case以及使用.分隔的key转换为参数使用的-分隔的名称，
+// 如 redis.max_idle 转换为 redis-max-idle
+func NormalizeKey(key string) string {
+	return keyNormalizer.Replace(key)
+}
+
+var keyNormalizer = strings.NewReplacer("_", "-", ".", "-")
+
+type loaderOptions struct {
+	keyMapper KeyMapper
+}
+
+type LoaderOption interface {
+	apply(o *loaderOptions)
+}
+
+type loaderOptionsFunc func(o *loaderOptions)
+
+func (of loaderOptionsFunc) apply(o *loaderOptions) { of(o) }
+
+// WithKeyMapper 使用mapper转换加载器读取到的所有配置项的key，
+// 如 box.WithKeyMapper(box.NormalizeKey) 使得 redis.max_idle 可以设置参数 redis-max-idle
+func WithKeyMapper(mapper KeyMapper) LoaderOption {
+	return loaderOptionsFunc(func(o *loaderOptions) {
+		o.keyMapper = mapper
+	})
+}
+
 type configLoaderBuilder struct {
 	ConfigLoader `flag:""`
 	source       flagx.Source
 	name         string
+	keyMapper    KeyMapper
+}
+
+// mapKeys 使用keyMapper转换配置项的key
+func (cb *configLoaderBuilder) mapKeys(items []ConfigItem) []ConfigItem {
+	if cb.keyMapper == nil {
+		return items
+	}
+	mapped := make([]ConfigItem, len(items))
+	for i := range items {
+		mapped[i] = ConfigItem{Key: cb.keyMapper(items[i].Key), Value: items[i].Value}
+	}
+	return mapped
+}
+
+// mapRemoved 使用keyMapper转换被删除的配置项的key
+func (cb *configLoaderBuilder) mapRemoved(keys []string) []string {
+	if cb.keyMapper == nil {
+		return keys
+	}
+	mapped := make([]string, len(keys))
+	for i := range keys {
+		mapped[i] = cb.keyMapper(keys[i])
+	}
+	return mapped
+}
+
+func (cb *configLoaderBuilder) unwrap() any {
+	return cb.ConfigLoader
 }
 
 func (cb *configLoaderBuilder) ValidateFlags() error {
@@ -35,3 +127,26 @@ func (cb *configLoaderBuilder) ValidateFlags() error {
 func (cb *configLoaderBuilder) Build(ctx context.Context) (*configLoaderBuilder, error) {
 	return cb, nil
 }
+
+// ChangedConfigItems 返回current中相对于last新增或者值发生变化的配置项，
+// 以及last中存在但current中已经删除的配置项的key
+func ChangedConfigItems(last, current []ConfigItem) (changed []ConfigItem, removed []string) {
+	lastValues := make(map[string]string, len(last))
+	for _, item := range last {
+		lastValues[item.Key] = item.Value
+	}
+	for _, item := range current {
+		value, ok := lastValues[item.Key]
+		if !ok || value != item.Value {
+			changed = append(changed, item)
+		}
+		delete(lastValues, item.Key)
+	}
+	for _, item := range last {
+		if _, ok := lastValues[item.Key]; ok {
+			removed = append(removed, item.Key)
+			delete(lastValues, item.Key)
+		}
+	}
+	return changed, removed
+}
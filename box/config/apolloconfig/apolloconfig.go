@@ -4,15 +4,22 @@ package apolloconfig
 import (
 	"context"
 	"fmt"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/jsonconfig"
 	"github.com/shima-park/agollo"
 	"golang.org/x/exp/slog"
+	"sigs.k8s.io/yaml"
 )
 
-func Init() box.BuildOption {
-	return box.UseConfigLoader("apollo", NewConfigLoader())
+// contentKey yaml、yml和json等非properties格式的namespace，apollo将整个文档保存在content中
+const contentKey = "content"
+
+func Init(opts ...box.LoaderOption) box.BuildOption {
+	return box.UseConfigLoader("apollo", NewConfigLoader(), opts...)
 }
 
 type ConfigLoader struct {
@@ -22,63 +29,51 @@ type ConfigLoader struct {
 	Namespace   string `flag:"namespace" default:"" usage:"apollo namespace, split by ',', previous namespace will override latest namespace" validate:"required"`
 	CachePath   string `flag:"cache" default:"./configs/apollo_system.json" usage:"apollo cache path"`
 	SyncTimeout int    `flag:"sync_timeout" default:"5" usage:"apollo sync timeout"`
-	Secret      string `flag:"secret" default:"" usage:"apollo secret" validate:"required"`
-
-	// namespaceList store all namespace by split Namespace
-	namespaceList []string
-	namespacesMap map[string]*namespace
-	// configKeyNamespace store namespace by config key
-	configKeyNamespace map[string]*namespace
-	client             agollo.Agollo
-	set                func([]box.ConfigItem)
+	Secret      string `flag:"secret" default:"" usage:"apollo secret" validate:"required" secret:"true"`
+
+	store  *store
+	client agollo.Agollo
 }
 
 func NewConfigLoader() *ConfigLoader {
-	return &ConfigLoader{
-		namespacesMap:      make(map[string]*namespace),
-		configKeyNamespace: make(map[string]*namespace),
-	}
+	return &ConfigLoader{}
 }
 
 func (cl *ConfigLoader) Load(ctx context.Context, set func([]box.ConfigItem)) error {
+	return cl.LoadUpdates(ctx, func(update box.ConfigUpdate) {
+		set(update.Items)
+	})
+}
+
+// LoadUpdates 加载所有namespace的配置，并监听变更，从所有namespace中删除的配置项会通过update.Removed报告
+func (cl *ConfigLoader) LoadUpdates(ctx context.Context, set func(update box.ConfigUpdate)) error {
 	if !strings.HasSuffix(cl.CachePath, ".json") {
 		cl.CachePath = cl.CachePath + ".json"
 	}
-	namespaces := strings.Split(cl.Namespace, ",")
-	for i := range namespaces {
-		cl.namespaceList = append(cl.namespaceList, namespaces[i])
-		cl.namespacesMap[namespaces[i]] = &namespace{name: namespaces[i], order: i}
-	}
-	cl.set = set
+	cl.store = newStore(strings.Split(cl.Namespace, ","))
 
 	if err := cl.connect(); err != nil {
 		return err
 	}
 
-	for _, n := range cl.namespaceList {
-		if err := cl.loadAndParseByNamespace(n); err != nil {
-			return err
+	var items []box.ConfigItem
+	for _, ns := range cl.store.namespaces {
+		nsItems, err := cl.parse(ns.name, cl.client.GetNameSpace(ns.name))
+		if err != nil {
+			return fmt.Errorf("namespace %s: %w", ns.name, err)
 		}
+		changed, _ := cl.store.update(ns, nsItems)
+		items = append(items, changed...)
 	}
+	set(box.ConfigUpdate{Items: items})
 
-	go cl.watch(ctx)
+	// 需要在Start之前创建监听channel，否则Start之后的变更可能不会被发送
+	resp := cl.client.Watch()
+	cl.client.Start()
+	go cl.watch(ctx, resp, set)
 	return nil
 }
 
-func (cl *ConfigLoader) setConfigItemsByNamespace(ns string, items []box.ConfigItem) {
-	namespace := cl.namespacesMap[ns]
-	itemsCanset := make([]box.ConfigItem, 0, len(items))
-	for _, item := range items {
-		ns, ok := cl.configKeyNamespace[item.Key]
-		if ok && ns.order < namespace.order {
-			continue
-		}
-		itemsCanset = append(itemsCanset, item)
-		cl.configKeyNamespace[item.Key] = namespace
-	}
-	cl.set(itemsCanset)
-}
-
 func (cl *ConfigLoader) connect() error {
 	client, err := agollo.New(
 		cl.Addr,
@@ -98,49 +93,75 @@ func (cl *ConfigLoader) connect() error {
 	return nil
 }
 
-func (cl *ConfigLoader) loadAndParseByNamespace(ns string) error {
-	cfg := cl.client.GetNameSpace(ns)
-	if len(cfg) == 0 {
-		return nil
-	}
-	items, err := cl.parse(cfg)
-	if err != nil {
-		return err
+// parse 解析namespace的配置，yaml、yml和json格式的namespace解析content中的文档，
+// 其他namespace作为properties处理，数值以及布尔类型的值会被转换为字符串
+func (cl *ConfigLoader) parse(ns string, cache agollo.Configurations) ([]box.ConfigItem, error) {
+	switch path.Ext(ns) {
+	case ".yaml", ".yml", ".json":
+		return parseContent(cache)
 	}
-	cl.setConfigItemsByNamespace(ns, items)
-	return nil
-}
-
-func (cl *ConfigLoader) parse(cache agollo.Configurations) ([]box.ConfigItem, error) {
 	var items = make([]box.ConfigItem, 0, len(cache))
 	for key, value := range cache {
-		strValue, ok := value.(string)
-		if !ok {
-			return nil, fmt.Errorf("apollo config value is not string, key: %s", key)
+		strValue, err := agollo.ToStringE(value)
+		if err != nil {
+			return nil, fmt.Errorf("apollo config value can not convert to string, key: %s", key)
 		}
 		items = append(items, box.ConfigItem{Key: key, Value: strValue})
 	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
 	return items, nil
 }
 
-func (cl *ConfigLoader) watch(ctx context.Context) {
-	// TODO: 这里以后需要注意namespace优先级
-	resp := cl.client.Watch()
-	for e := range resp {
+// parseContent 解析yaml或者json格式namespace的content，json是yaml的子集，所以统一按照yaml解析
+func parseContent(cache agollo.Configurations) ([]box.ConfigItem, error) {
+	content, ok := cache[contentKey]
+	if !ok {
+		return nil, nil
+	}
+	strContent, ok := content.(string)
+	if !ok {
+		return nil, fmt.Errorf("apollo namespace content is not string")
+	}
+	if strings.TrimSpace(strContent) == "" {
+		return nil, nil
+	}
+	jsonRawConfig, err := yaml.YAMLToJSON([]byte(strContent))
+	if err != nil {
+		return nil, fmt.Errorf("apollo namespace content parse failed: %w", err)
+	}
+	return jsonconfig.ParseJSONToKeyValue(string(jsonRawConfig))
+}
+
+// watch 监听所有namespace的变更，使用变更后namespace的全部配置重新计算生效值，
+// 从而正确处理namespace之间的优先级以及配置项的删除，ctx结束时停止监听
+func (cl *ConfigLoader) watch(ctx context.Context, resp <-chan *agollo.ApolloResponse, set func(update box.ConfigUpdate)) {
+	defer cl.client.Stop()
+	for {
+		var e *agollo.ApolloResponse
+		select {
+		case <-ctx.Done():
+			return
+		case e = <-resp:
+		}
+		if e.Error != nil {
+			slog.Warn("watch apollo namespace failed", "namespace", e.Namespace, "error", e.Error)
+			continue
+		}
 		slog.Debug("config has changed", "namespace", e.Namespace, "e", e.Changes)
-		items := make([]box.ConfigItem, 0, len(e.Changes))
-		for _, item := range e.Changes {
-			if item.Value == nil {
-				continue
-			}
-			strValue, ok := item.Value.(string)
-			if !ok {
-				slog.Error("apollo config value is not string", "key", item.Key)
-				continue
-			}
-			items = append(items, box.ConfigItem{Key: item.Key, Value: strValue})
+		ns := cl.store.lookup(e.Namespace)
+		if ns == nil {
+			slog.Warn("unknown apollo namespace is ignored", "namespace", e.Namespace)
+			continue
+		}
+		items, err := cl.parse(ns.name, e.NewValue)
+		if err != nil {
+			slog.Error("parse apollo namespace failed", "namespace", e.Namespace, "error", err)
+			continue
+		}
+		changed, removed := cl.store.update(ns, items)
+		if len(changed) > 0 || len(removed) > 0 {
+			set(box.ConfigUpdate{Items: changed, Removed: removed})
 		}
-		cl.setConfigItemsByNamespace(e.Namespace, items)
 	}
 }
 
@@ -0,0 +1,159 @@
+package apolloconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/internal/loadertest"
+)
+
+// apolloServer 模拟apollo config service的配置接口和通知接口
+type apolloServer struct {
+	mux           sync.Mutex
+	configs       map[string]map[string]any
+	notifications map[string]int
+}
+
+func newApolloServer() *apolloServer {
+	return &apolloServer{configs: make(map[string]map[string]any), notifications: make(map[string]int)}
+}
+
+func (s *apolloServer) publish(namespace string, configs map[string]any) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.configs[namespace] = configs
+	s.notifications[namespace]++
+}
+
+func (s *apolloServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/configs/"):
+		s.serveConfigs(w, r)
+	case r.URL.Path == "/notifications/v2":
+		s.serveNotifications(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveConfigs 处理 /configs/{appId}/{cluster}/{namespace}，使用通知ID作为releaseKey
+func (s *apolloServer) serveConfigs(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/configs/"), "/")
+	namespace := parts[len(parts)-1]
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	configs, ok := s.configs[namespace]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	releaseKey := strconv.Itoa(s.notifications[namespace])
+	if r.URL.Query().Get("releaseKey") == releaseKey {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"appId":          parts[0],
+		"cluster":        parts[1],
+		"namespaceName":  namespace,
+		"configurations": configs,
+		"releaseKey":     releaseKey,
+	})
+}
+
+// serveNotifications 返回通知ID大于客户端的namespace，没有变更时短暂挂起后返回304
+func (s *apolloServer) serveNotifications(w http.ResponseWriter, r *http.Request) {
+	var local []struct {
+		NamespaceName  string `json:"namespaceName"`
+		NotificationID int    `json:"notificationId"`
+	}
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("notifications")), &local); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for {
+		var changed []map[string]any
+		s.mux.Lock()
+		for _, n := range local {
+			if id, ok := s.notifications[n.NamespaceName]; ok && id > n.NotificationID {
+				changed = append(changed, map[string]any{"namespaceName": n.NamespaceName, "notificationId": id})
+			}
+		}
+		s.mux.Unlock()
+		if len(changed) > 0 {
+			_ = json.NewEncoder(w).Encode(changed)
+			return
+		}
+		if time.Now().After(deadline) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newTestConfigLoader(addr, cachePath string) *ConfigLoader {
+	cl := NewConfigLoader()
+	cl.AppId = "app"
+	cl.Cluster = "default"
+	cl.Addr = addr
+	cl.Namespace = "override,application,redis.yaml"
+	cl.CachePath = cachePath
+	cl.Secret = "secret"
+	return cl
+}
+
+func TestLoadAndWatch(t *testing.T) {
+	as := newApolloServer()
+	as.publish("override", map[string]any{"addr": "b"})
+	as.publish("application", map[string]any{"addr": "a", "port": 8080})
+	as.publish("redis.yaml", map[string]any{"content": "redis:\n  addr: r\n  hosts: [h1, h2]\n"})
+	server := httptest.NewServer(as)
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "apollo.json")
+	cl := newTestConfigLoader(server.URL, cachePath)
+	want := []box.ConfigItem{
+		{Key: "addr", Value: "b"},
+		{Key: "port", Value: "8080"},
+		{Key: "redis-addr", Value: "r"},
+		{Key: "redis-hosts", Value: "h1,h2\n"},
+	}
+	loadertest.Watch(t, cl.LoadUpdates, box.ConfigUpdate{Items: want}, []loadertest.Step[box.ConfigUpdate]{
+		{
+			// 删除高优先级namespace中的配置项后恢复低优先级namespace中的值
+			Name:   "override removed",
+			Change: func() { as.publish("override", map[string]any{}) },
+			Want:   box.ConfigUpdate{Items: []box.ConfigItem{{Key: "addr", Value: "a"}}},
+		},
+		{
+			Name:   "yaml changed",
+			Change: func() { as.publish("redis.yaml", map[string]any{"content": "redis:\n  addr: r2\n  hosts: [h1, h2]\n"}) },
+			Want:   box.ConfigUpdate{Items: []box.ConfigItem{{Key: "redis-addr", Value: "r2"}}},
+		},
+		{
+			// 从所有namespace中删除的配置项需要报告删除
+			Name:   "removed from all namespaces",
+			Change: func() { as.publish("application", map[string]any{"addr": "a"}) },
+			Want:   box.ConfigUpdate{Removed: []string{"port"}},
+		},
+	})
+
+	// apollo不可用时使用备份文件启动
+	server.Close()
+	cl = newTestConfigLoader(server.URL, cachePath)
+	loadertest.Load(t, cl.Load, []box.ConfigItem{
+		{Key: "addr", Value: "a"},
+		{Key: "redis-addr", Value: "r2"},
+		{Key: "redis-hosts", Value: "h1,h2\n"},
+	})
+}
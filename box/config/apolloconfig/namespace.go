@@ -1,6 +1,83 @@
 package apolloconfig
 
+import (
+	"sort"
+	"strings"
+
+	"github.com/daemtri/di/box"
+)
+
+// namespace 保存一个namespace当前的所有配置项
 type namespace struct {
 	name  string
-	order int
+	items map[string]string
+}
+
+// store 按namespace的优先级计算每个配置项的生效值
+type store struct {
+	// namespaces 按优先级从高到低排序
+	namespaces []*namespace
+	// effective 保存已经设置的生效值
+	effective map[string]string
+}
+
+func newStore(names []string) *store {
+	s := &store{effective: make(map[string]string)}
+	for _, name := range names {
+		s.namespaces = append(s.namespaces, &namespace{name: name, items: make(map[string]string)})
+	}
+	return s
+}
+
+// lookup 查找namespace，apollo通知的properties类型namespace可能没有.properties后缀
+func (s *store) lookup(name string) *namespace {
+	for _, ns := range s.namespaces {
+		if strings.TrimSuffix(ns.name, ".properties") == strings.TrimSuffix(name, ".properties") {
+			return ns
+		}
+	}
+	return nil
+}
+
+// resolve 返回优先级最高的namespace中key的值
+func (s *store) resolve(key string) (string, bool) {
+	for _, ns := range s.namespaces {
+		if value, ok := ns.items[key]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// update 使用items替换namespace的所有配置项，重新计算受影响的key的生效值，
+// 返回生效值发生变化的配置项，以及不再存在于任何namespace中的key
+func (s *store) update(ns *namespace, items []box.ConfigItem) (changed []box.ConfigItem, removed []string) {
+	keys := make(map[string]struct{}, len(ns.items)+len(items))
+	for key := range ns.items {
+		keys[key] = struct{}{}
+	}
+	ns.items = make(map[string]string, len(items))
+	for _, item := range items {
+		ns.items[item.Key] = item.Value
+		keys[item.Key] = struct{}{}
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		value, ok := s.resolve(key)
+		current, exists := s.effective[key]
+		switch {
+		case !ok && exists:
+			delete(s.effective, key)
+			removed = append(removed, key)
+		case ok && (!exists || current != value):
+			s.effective[key] = value
+			changed = append(changed, box.ConfigItem{Key: key, Value: value})
+		}
+	}
+	return changed, removed
 }
@@ -0,0 +1,65 @@
+package apolloconfig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/daemtri/di/box"
+)
+
+func TestStoreUpdate(t *testing.T) {
+	s := newStore([]string{"override", "application"})
+	steps := []struct {
+		name        string
+		namespace   string
+		items       []box.ConfigItem
+		wantChanged []box.ConfigItem
+		wantRemoved []string
+	}{
+		{
+			name:        "load lower namespace",
+			namespace:   "application",
+			items:       []box.ConfigItem{{Key: "addr", Value: "a"}, {Key: "db", Value: "1"}},
+			wantChanged: []box.ConfigItem{{Key: "addr", Value: "a"}, {Key: "db", Value: "1"}},
+		},
+		{
+			name:        "higher namespace overrides",
+			namespace:   "override",
+			items:       []box.ConfigItem{{Key: "addr", Value: "b"}},
+			wantChanged: []box.ConfigItem{{Key: "addr", Value: "b"}},
+		},
+		{
+			name:      "lower namespace change is shadowed",
+			namespace: "application",
+			items:     []box.ConfigItem{{Key: "addr", Value: "c"}, {Key: "db", Value: "1"}},
+		},
+		{
+			name:        "delete from higher namespace restores lower value",
+			namespace:   "override.properties",
+			items:       nil,
+			wantChanged: []box.ConfigItem{{Key: "addr", Value: "c"}},
+		},
+		{
+			name:        "delete from all namespaces",
+			namespace:   "application",
+			items:       []box.ConfigItem{{Key: "addr", Value: "c"}},
+			wantRemoved: []string{"db"},
+		},
+	}
+	for _, step := range steps {
+		ns := s.lookup(step.namespace)
+		if ns == nil {
+			t.Fatalf("%s: namespace %s not found", step.name, step.namespace)
+		}
+		changed, removed := s.update(ns, step.items)
+		if !reflect.DeepEqual(changed, step.wantChanged) {
+			t.Errorf("%s: changed = %v, want %v", step.name, changed, step.wantChanged)
+		}
+		if !reflect.DeepEqual(removed, step.wantRemoved) {
+			t.Errorf("%s: removed = %v, want %v", step.name, removed, step.wantRemoved)
+		}
+	}
+	if s.lookup("unknown") != nil {
+		t.Error("lookup(unknown) expected nil")
+	}
+}
@@ -0,0 +1,190 @@
+// Package consulconfig Consul KV配置加载器，使用阻塞查询监听prefix下的键值变更，
+// 如prefix为services/user/时，services/user/redis/addr对应的配置项为redis-addr
+package consulconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/internal/cachefile"
+	"golang.org/x/exp/slog"
+)
+
+func Init(opts ...box.LoaderOption) box.BuildOption {
+	return box.UseConfigLoader("consul", &ConfigLoader{}, opts...)
+}
+
+type ConfigLoader struct {
+	Addr          string        `flag:"addr" default:"http://127.0.0.1:8500" usage:"consul地址" validate:"required,url"`
+	Token         string        `flag:"token" default:"" usage:"consul ACL token" secret:"true"`
+	Datacenter    string        `flag:"datacenter" default:"" usage:"consul datacenter，为空时使用agent所在的datacenter"`
+	Prefix        string        `flag:"prefix" default:"" usage:"配置项key的前缀，如services/user/" validate:"required"`
+	CachePath     string        `flag:"cache" default:"./configs/consul_config.json" usage:"consul配置的本地备份路径，consul不可用时使用备份启动"`
+	Watch         bool          `flag:"watch" default:"false" usage:"是否监听prefix下的键值变更，变更的配置项会被重新设置"`
+	WaitTime      time.Duration `flag:"wait-time" default:"5m" usage:"阻塞查询的最长等待时间"`
+	Timeout       time.Duration `flag:"timeout" default:"10s" usage:"查询的超时时间，阻塞查询的超时时间为wait-time加上该时间"`
+	RetryInterval time.Duration `flag:"retry-interval" default:"5s" usage:"查询失败后的重试间隔"`
+
+	client *http.Client
+}
+
+// kvPair consul KV接口返回的键值对，Value为base64编码，json解码时会自动解码
+type kvPair struct {
+	Key   string
+	Value []byte
+}
+
+func (c *ConfigLoader) Load(ctx context.Context, setter func([]box.ConfigItem)) error {
+	return c.LoadUpdates(ctx, func(update box.ConfigUpdate) {
+		setter(update.Items)
+	})
+}
+
+// LoadUpdates 加载prefix下的所有键值，Watch为true时监听变更，从consul中删除的key会通过update.Removed报告
+func (c *ConfigLoader) LoadUpdates(ctx context.Context, setter func(update box.ConfigUpdate)) error {
+	c.client = &http.Client{}
+	items, index, err := c.fetch(ctx, 0)
+	if err != nil {
+		slog.Warn("load consul config failed, fallback to backup", "addr", c.Addr, "cache", c.CachePath, "error", err)
+		items, err = c.loadBackup()
+		if err != nil {
+			return err
+		}
+	}
+	setter(box.ConfigUpdate{Items: items})
+	if c.Watch {
+		go c.watch(ctx, items, index, setter)
+	}
+	return nil
+}
+
+// watch 使用阻塞查询监听prefix下的键值变更，只设置发生变化以及被删除的配置项，直到ctx结束
+func (c *ConfigLoader) watch(ctx context.Context, last []box.ConfigItem, index uint64, setter func(update box.ConfigUpdate)) {
+	for ctx.Err() == nil {
+		current, newIndex, err := c.fetch(ctx, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("watch consul config failed", "addr", c.Addr, "prefix", c.Prefix, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.RetryInterval):
+			}
+			continue
+		}
+		// index变小时说明consul的数据被重置，需要重新开始阻塞查询
+		if newIndex < index {
+			newIndex = 0
+		}
+		if newIndex == index {
+			continue
+		}
+		index = newIndex
+		changed, removed := box.ChangedConfigItems(last, current)
+		last = current
+		if len(changed) > 0 || len(removed) > 0 {
+			setter(box.ConfigUpdate{Items: changed, Removed: removed})
+		}
+	}
+}
+
+// fetch 查询prefix下的所有键值，index大于0时使用阻塞查询，直到index变化或者超过WaitTime，
+// 查询超过Timeout（阻塞查询为WaitTime加上Timeout）时返回错误，成功后更新本地备份
+func (c *ConfigLoader) fetch(ctx context.Context, index uint64) ([]box.ConfigItem, uint64, error) {
+	query := url.Values{"recurse": []string{"true"}}
+	if c.Datacenter != "" {
+		query.Set("dc", c.Datacenter)
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", c.WaitTime.String())
+	}
+	// consul会在wait的基础上增加最多wait/16的随机等待时间，阻塞查询的超时时间需要在此基础上加上Timeout，
+	// 首次查询没有超时的话，consul不可用时会一直挂起，无法使用备份启动
+	timeout := c.Timeout
+	if index > 0 {
+		timeout += c.WaitTime + c.WaitTime/16
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	reqURL := strings.TrimSuffix(c.Addr, "/") + "/v1/kv/" + strings.TrimPrefix(c.Prefix, "/") + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if body, err = io.ReadAll(resp.Body); err != nil {
+			return nil, 0, err
+		}
+	case http.StatusNotFound:
+		// prefix下没有任何key
+		body = []byte("[]")
+	default:
+		return nil, 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	newIndex, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid X-Consul-Index header: %w", err)
+	}
+	items, err := c.parse(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cachefile.Write(c.CachePath, body); err != nil {
+		slog.Warn("save consul config backup failed", "cache", c.CachePath, "error", err)
+	}
+	return items, newIndex, nil
+}
+
+// parse 解析consul KV接口返回的内容，忽略以/结尾的目录key
+func (c *ConfigLoader) parse(body []byte) ([]box.ConfigItem, error) {
+	var pairs []kvPair
+	if err := json.Unmarshal(body, &pairs); err != nil {
+		return nil, fmt.Errorf("consul配置解析失败: %w", err)
+	}
+	items := make([]box.ConfigItem, 0, len(pairs))
+	for _, pair := range pairs {
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		key := strings.Trim(strings.TrimPrefix(pair.Key, strings.TrimPrefix(c.Prefix, "/")), "/")
+		if key == "" {
+			continue
+		}
+		items = append(items, box.ConfigItem{Key: strings.ReplaceAll(key, "/", "-"), Value: string(pair.Value)})
+	}
+	return items, nil
+}
+
+func (c *ConfigLoader) loadBackup() ([]box.ConfigItem, error) {
+	body, err := os.ReadFile(c.CachePath)
+	if err != nil {
+		return nil, fmt.Errorf("consul配置备份读取失败: %w", err)
+	}
+	return c.parse(body)
+}
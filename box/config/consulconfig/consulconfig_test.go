@@ -0,0 +1,160 @@
+package consulconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/internal/loadertest"
+)
+
+// consulServer 模拟consul KV接口，支持recurse和阻塞查询
+type consulServer struct {
+	mux     sync.Mutex
+	index   uint64
+	data    map[string]string
+	changed chan struct{}
+}
+
+func newConsulServer() *consulServer {
+	return &consulServer{index: 1, data: make(map[string]string), changed: make(chan struct{})}
+}
+
+func (s *consulServer) put(key, value string) {
+	s.update(func() { s.data[key] = value })
+}
+
+func (s *consulServer) delete(key string) {
+	s.update(func() { delete(s.data, key) })
+}
+
+func (s *consulServer) update(fn func()) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	fn()
+	s.index++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *consulServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Consul-Token") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	if index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); index > 0 {
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		s.mux.Lock()
+		changed, current := s.changed, s.index
+		s.mux.Unlock()
+		if index >= current {
+			select {
+			case <-changed:
+			case <-time.After(wait):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	type kvPair struct {
+		Key   string
+		Value []byte
+	}
+	pairs := make([]kvPair, 0, len(s.data))
+	for key, value := range s.data {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, kvPair{Key: key, Value: []byte(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(s.index, 10))
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func TestLoadAndWatch(t *testing.T) {
+	cs := newConsulServer()
+	cs.put("services/user/redis/addr", "a")
+	cs.put("services/user/redis/db", "1")
+	cs.put("services/order/redis/addr", "b")
+	server := httptest.NewServer(cs)
+	defer server.Close()
+
+	c := &ConfigLoader{
+		Addr:          server.URL,
+		Token:         "token",
+		Prefix:        "services/user/",
+		CachePath:     filepath.Join(t.TempDir(), "consul.json"),
+		Watch:         true,
+		WaitTime:      time.Second,
+		RetryInterval: 10 * time.Millisecond,
+	}
+	loadertest.Watch(t, c.LoadUpdates, box.ConfigUpdate{Items: []box.ConfigItem{{Key: "redis-addr", Value: "a"}, {Key: "redis-db", Value: "1"}}}, []loadertest.Step[box.ConfigUpdate]{
+		{
+			Name: "changed",
+			Change: func() {
+				cs.put("services/order/redis/addr", "c")
+				cs.put("services/user/redis/addr", "d")
+			},
+			Want: box.ConfigUpdate{Items: []box.ConfigItem{{Key: "redis-addr", Value: "d"}}},
+		},
+		{
+			Name:   "removed",
+			Change: func() { cs.delete("services/user/redis/db") },
+			Want:   box.ConfigUpdate{Removed: []string{"redis-db"}},
+		},
+	})
+
+	// consul不可用时使用备份启动
+	server.Close()
+	c2 := &ConfigLoader{Addr: server.URL, Prefix: "services/user/", CachePath: c.CachePath}
+	loadertest.Load(t, c2.Load, []box.ConfigItem{{Key: "redis-addr", Value: "d"}})
+}
+
+func TestLoadUnauthorized(t *testing.T) {
+	server := httptest.NewServer(newConsulServer())
+	defer server.Close()
+	c := &ConfigLoader{Addr: server.URL, Prefix: "services/user/", CachePath: filepath.Join(t.TempDir(), "consul.json")}
+	if err := c.Load(context.Background(), func([]box.ConfigItem) {}); err == nil {
+		t.Error("Load() expected error")
+	}
+}
+
+func TestLoadTimeoutFallback(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 模拟挂起的consul
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cachePath := filepath.Join(t.TempDir(), "consul.json")
+	backup := `[{"Key":"services/user/redis/addr","Value":"` + base64.StdEncoding.EncodeToString([]byte("a")) + `"}]`
+	if err := os.WriteFile(cachePath, []byte(backup), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &ConfigLoader{Addr: server.URL, Prefix: "services/user/", CachePath: cachePath, Timeout: 50 * time.Millisecond}
+	loadertest.Load(t, c.Load, []box.ConfigItem{{Key: "redis-addr", Value: "a"}})
+}
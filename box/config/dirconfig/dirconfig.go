@@ -0,0 +1,129 @@
+// Package dirconfig 目录配置加载器，目录下的每个文件对应一个配置项，
+// 适用于Kubernetes以volume方式挂载的ConfigMap和Secret
+package dirconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"golang.org/x/exp/slog"
+)
+
+// dataLink Kubernetes挂载ConfigMap和Secret时，通过原子的替换该符号链接来更新目录下的所有文件
+const dataLink = "..data"
+
+func Init(opts ...box.LoaderOption) box.BuildOption {
+	return box.UseConfigLoader("dir", &ConfigLoader{}, opts...)
+}
+
+type ConfigLoader struct {
+	Path          string        `flag:"path" default:"" usage:"配置目录路径，目录下的每个文件为一个配置项，文件名为key，文件内容为value" validate:"required"`
+	Prefix        string        `flag:"prefix" default:"" usage:"配置项key的前缀，如prefix为redis时，文件addr对应的key为redis-addr"`
+	Watch         bool          `flag:"watch" default:"false" usage:"是否监听配置目录变更，变更的配置项会被重新设置"`
+	WatchInterval time.Duration `flag:"watch-interval" default:"5s" usage:"监听配置目录变更的轮询间隔" validate:"gt=0"`
+}
+
+func (c *ConfigLoader) Load(ctx context.Context, setter func([]box.ConfigItem)) error {
+	return c.LoadUpdates(ctx, func(update box.ConfigUpdate) {
+		setter(update.Items)
+	})
+}
+
+// LoadUpdates 加载目录下的配置，Watch为true时监听变更，被删除的文件会通过update.Removed报告
+func (c *ConfigLoader) LoadUpdates(ctx context.Context, setter func(update box.ConfigUpdate)) error {
+	// 先读取链接目标再加载，加载过程中发生的替换会在下次检查时重新加载
+	target, hasDataLink := readDataLink(c.Path)
+	items, err := Load(c.Path, c.Prefix)
+	if err != nil {
+		return err
+	}
+	setter(box.ConfigUpdate{Items: items})
+	if c.Watch {
+		go c.watch(ctx, items, target, hasDataLink, setter)
+	}
+	return nil
+}
+
+// watch 定时检查配置目录，只设置发生变化以及被删除的配置项，直到ctx结束
+// 目录存在..data符号链接时，只有当链接目标变化时才重新加载
+func (c *ConfigLoader) watch(ctx context.Context, last []box.ConfigItem, lastTarget string, hasDataLink bool, setter func(update box.ConfigUpdate)) {
+	ticker := time.NewTicker(c.WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		target, ok := lastTarget, hasDataLink
+		if hasDataLink {
+			target, ok = readDataLink(c.Path)
+			if ok && target == lastTarget {
+				continue
+			}
+		}
+		current, err := Load(c.Path, c.Prefix)
+		if err != nil {
+			slog.Warn("reload config dir failed", "path", c.Path, "error", err)
+			continue
+		}
+		// 加载成功之后才记录链接目标，加载失败时下次检查会重新加载
+		lastTarget, hasDataLink = target, ok
+		changed, removed := box.ChangedConfigItems(last, current)
+		last = current
+		if len(changed) > 0 || len(removed) > 0 {
+			setter(box.ConfigUpdate{Items: changed, Removed: removed})
+		}
+	}
+}
+
+func readDataLink(dir string) (string, bool) {
+	target, err := os.Readlink(filepath.Join(dir, dataLink))
+	if err != nil {
+		return "", false
+	}
+	return target, true
+}
+
+// Load 读取目录下的所有文件作为配置项，忽略子目录以及以.开头的隐藏文件，
+// 文件内容末尾的换行符会被去除，prefix不为空时key为 <prefix>-<文件名>
+func Load(dir string, prefix string) ([]box.ConfigItem, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("配置目录读取失败: %w", err)
+	}
+	items := make([]box.ConfigItem, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		// Kubernetes挂载的文件是指向..data目录下文件的符号链接，需要使用Stat判断链接目标的类型
+		file := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(file)
+		if err != nil {
+			// 删除的文件在..data替换之后，符号链接被清理之前，是指向不存在文件的符号链接
+			if os.IsNotExist(err) && entry.Type()&os.ModeSymlink != 0 {
+				continue
+			}
+			return nil, fmt.Errorf("配置文件读取失败: %w", err)
+		}
+		if info.IsDir() {
+			continue
+		}
+		value, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("配置文件读取失败: %w", err)
+		}
+		key := entry.Name()
+		if prefix != "" {
+			key = prefix + "-" + key
+		}
+		items = append(items, box.ConfigItem{Key: key, Value: strings.TrimRight(string(value), "\r\n")})
+	}
+	return items, nil
+}
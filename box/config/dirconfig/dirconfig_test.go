@@ -0,0 +1,91 @@
+package dirconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/internal/loadertest"
+)
+
+// writeData 模拟Kubernetes更新挂载目录：写入新的数据目录，然后原子的替换..data符号链接
+func writeData(t *testing.T, dir, version string, files map[string]string) {
+	t.Helper()
+	dataDir := filepath.Join(dir, "..."+version)
+	if err := os.Mkdir(dataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dataDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			if err := os.Symlink(filepath.Join(dataLink, name), link); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tmpLink := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(filepath.Base(dataDir), tmpLink); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpLink, filepath.Join(dir, dataLink)); err != nil {
+		t.Fatal(err)
+	}
+	// 替换..data之后清理已经删除的文件的符号链接
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if _, ok := files[entry.Name()]; !ok && entry.Type()&os.ModeSymlink != 0 && entry.Name() != dataLink {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeData(t, dir, "1", map[string]string{"addr": "127.0.0.1:6379\n", "db": "1"})
+	if err := os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 已经删除但符号链接还未被清理的文件
+	if err := os.Symlink(filepath.Join(dataLink, "removed"), filepath.Join(dir, "removed")); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := Load(dir, "redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []box.ConfigItem{{Key: "redis-addr", Value: "127.0.0.1:6379"}, {Key: "redis-db", Value: "1"}}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Load() = %v, want %v", items, want)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeData(t, dir, "1", map[string]string{"addr": "a", "db": "1"})
+
+	c := &ConfigLoader{Path: dir, Watch: true, WatchInterval: 10 * time.Millisecond}
+	loadertest.Watch(t, c.LoadUpdates, box.ConfigUpdate{Items: []box.ConfigItem{{Key: "addr", Value: "a"}, {Key: "db", Value: "1"}}}, []loadertest.Step[box.ConfigUpdate]{
+		{
+			Name:   "changed",
+			Change: func() { writeData(t, dir, "2", map[string]string{"addr": "b", "db": "1"}) },
+			Want:   box.ConfigUpdate{Items: []box.ConfigItem{{Key: "addr", Value: "b"}}},
+		},
+		{
+			Name:   "removed",
+			Change: func() { writeData(t, dir, "3", map[string]string{"addr": "b"}) },
+			Want:   box.ConfigUpdate{Removed: []string{"db"}},
+		},
+	})
+}
@@ -0,0 +1,146 @@
+// Package httpconfig HTTP配置加载器，从URL获取JSON或YAML格式的配置文档
+package httpconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/internal/cachefile"
+	"github.com/daemtri/di/box/config/jsonconfig"
+	"golang.org/x/exp/slog"
+	"sigs.k8s.io/yaml"
+)
+
+// errNotModified 服务端返回304，配置文档没有变化
+var errNotModified = errors.New("not modified")
+
+func Init(opts ...box.LoaderOption) box.BuildOption {
+	return box.UseConfigLoader("http", &ConfigLoader{}, opts...)
+}
+
+type ConfigLoader struct {
+	URL           string        `flag:"url" default:"" usage:"配置文档地址，支持JSON和YAML格式" validate:"required,url"`
+	Token         string        `flag:"token" default:"" usage:"请求配置文档时使用的Bearer Token" secret:"true"`
+	Timeout       time.Duration `flag:"timeout" default:"30s" usage:"请求配置文档的超时时间，服务端使用长轮询时需要大于服务端挂起请求的时间"`
+	CachePath     string        `flag:"cache" default:"./configs/http_config.cache" usage:"配置文档的本地缓存路径，请求失败时使用缓存启动"`
+	Watch         bool          `flag:"watch" default:"false" usage:"是否监听配置文档变更，变更的配置项会被重新设置"`
+	WatchInterval time.Duration `flag:"watch-interval" default:"5s" usage:"监听配置文档变更的轮询间隔" validate:"gt=0"`
+
+	client *http.Client
+	etag   string
+}
+
+func (c *ConfigLoader) Load(ctx context.Context, setter func([]box.ConfigItem)) error {
+	return c.LoadUpdates(ctx, func(update box.ConfigUpdate) {
+		setter(update.Items)
+	})
+}
+
+// LoadUpdates 加载配置文档，Watch为true时监听变更，从文档中删除的配置项会通过update.Removed报告
+func (c *ConfigLoader) LoadUpdates(ctx context.Context, setter func(update box.ConfigUpdate)) error {
+	c.client = &http.Client{Timeout: c.Timeout}
+	items, err := c.fetch(ctx)
+	if err != nil {
+		slog.Warn("fetch config document failed, fallback to cache", "url", c.URL, "cache", c.CachePath, "error", err)
+		items, err = c.loadCache()
+		if err != nil {
+			return err
+		}
+	}
+	setter(box.ConfigUpdate{Items: items})
+	if c.Watch {
+		go c.watch(ctx, items, setter)
+	}
+	return nil
+}
+
+// watch 定时使用If-None-Match请求配置文档，只设置发生变化以及被删除的配置项，直到ctx结束
+func (c *ConfigLoader) watch(ctx context.Context, last []box.ConfigItem, setter func(update box.ConfigUpdate)) {
+	ticker := time.NewTicker(c.WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current, err := c.fetch(ctx)
+		if err != nil {
+			if !errors.Is(err, errNotModified) && ctx.Err() == nil {
+				slog.Warn("fetch config document failed", "url", c.URL, "error", err)
+			}
+			continue
+		}
+		changed, removed := box.ChangedConfigItems(last, current)
+		last = current
+		if len(changed) > 0 || len(removed) > 0 {
+			setter(box.ConfigUpdate{Items: changed, Removed: removed})
+		}
+	}
+}
+
+// fetch 请求并解析配置文档，成功后更新本地缓存，文档没有变化时返回errNotModified
+func (c *ConfigLoader) fetch(ctx context.Context) ([]box.ConfigItem, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, errNotModified
+	default:
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	items, err := Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	c.etag = resp.Header.Get("ETag")
+	if err := cachefile.Write(c.CachePath, body); err != nil {
+		slog.Warn("save config document cache failed", "cache", c.CachePath, "error", err)
+	}
+	return items, nil
+}
+
+func (c *ConfigLoader) loadCache() ([]box.ConfigItem, error) {
+	body, err := os.ReadFile(c.CachePath)
+	if err != nil {
+		return nil, fmt.Errorf("配置缓存读取失败: %w", err)
+	}
+	return Parse(body)
+}
+
+// Parse 解析JSON或者YAML格式的配置文档为配置项
+func Parse(document []byte) ([]box.ConfigItem, error) {
+	jsonRawConfig, err := yaml.YAMLToJSON(document)
+	if err != nil {
+		return nil, fmt.Errorf("配置文档解析失败: %w", err)
+	}
+	items, err := jsonconfig.ParseJSONToKeyValue(string(jsonRawConfig))
+	if err != nil {
+		return nil, fmt.Errorf("配置文档解析失败: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,84 @@
+package httpconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/internal/loadertest"
+)
+
+// configServer 模拟配置服务，使用文档版本作为ETag
+type configServer struct {
+	mux      sync.Mutex
+	version  string
+	document string
+}
+
+func (s *configServer) set(version, document string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.version, s.document = version, document
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("If-None-Match") == s.version {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", s.version)
+	_, _ = w.Write([]byte(s.document))
+}
+
+func TestLoadAndWatch(t *testing.T) {
+	cs := &configServer{}
+	cs.set(`"1"`, "redis:\n  addr: a\n  db: 1\n")
+	server := httptest.NewServer(cs)
+	defer server.Close()
+
+	c := &ConfigLoader{
+		URL:           server.URL,
+		Token:         "token",
+		Timeout:       time.Second,
+		CachePath:     filepath.Join(t.TempDir(), "config.cache"),
+		Watch:         true,
+		WatchInterval: 10 * time.Millisecond,
+	}
+	loadertest.Watch(t, c.LoadUpdates, box.ConfigUpdate{Items: []box.ConfigItem{{Key: "redis-addr", Value: "a"}, {Key: "redis-db", Value: "1"}}}, []loadertest.Step[box.ConfigUpdate]{
+		{
+			Name:   "changed",
+			Change: func() { cs.set(`"2"`, `{"redis": {"addr": "b", "db": 1}}`) },
+			Want:   box.ConfigUpdate{Items: []box.ConfigItem{{Key: "redis-addr", Value: "b"}}},
+		},
+		{
+			Name:   "removed",
+			Change: func() { cs.set(`"3"`, `{"redis": {"addr": "b"}}`) },
+			Want:   box.ConfigUpdate{Removed: []string{"redis-db"}},
+		},
+	})
+
+	// 配置服务不可用时使用缓存启动
+	server.Close()
+	c2 := &ConfigLoader{URL: server.URL, Timeout: time.Second, CachePath: c.CachePath}
+	loadertest.Load(t, c2.Load, []box.ConfigItem{{Key: "redis-addr", Value: "b"}})
+}
+
+func TestLoadWithoutCache(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	c := &ConfigLoader{URL: server.URL, Timeout: time.Second, CachePath: filepath.Join(t.TempDir(), "config.cache")}
+	if err := c.Load(context.Background(), func([]box.ConfigItem) {}); err == nil {
+		t.Error("Load() expected error")
+	}
+}
@@ -0,0 +1,23 @@
+// Package cachefile 配置加载器的本地缓存文件，远程配置不可用时使用缓存启动
+package cachefile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Write 将data写入path，path为空时不写入，
+// 先写入临时文件再重命名，避免进程退出时留下不完整的缓存
+func Write(path string, data []byte) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,29 @@
+package cachefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configs", "cache.json")
+	for _, data := range []string{`{"a":1}`, `{"a":2}`} {
+		if err := Write(path, []byte(data)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != data {
+			t.Errorf("cache = %s, want %s", got, data)
+		}
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left: %v", err)
+	}
+	if err := Write("", []byte("ignored")); err != nil {
+		t.Errorf("Write with empty path: %v", err)
+	}
+}
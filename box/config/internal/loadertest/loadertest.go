@@ -0,0 +1,54 @@
+// Package loadertest 配置加载器测试的公共步骤：加载配置，依次修改配置来源并检查收到的更新
+package loadertest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Timeout 等待每次更新的最长时间
+var Timeout = 5 * time.Second
+
+// Step 一次配置来源的修改，以及修改后期望收到的更新
+type Step[T any] struct {
+	Name   string
+	Change func()
+	Want   T
+}
+
+// Watch 使用load加载配置并检查首次收到的配置为want，然后依次执行steps并检查每次收到的更新，
+// load为加载器的Load、LoadUpdates或者LoadLayers，返回前取消传给load的ctx，停止监听
+func Watch[T any](t *testing.T, load func(ctx context.Context, setter func(T)) error, want T, steps []Step[T]) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan T, len(steps)+1)
+	if err := load(ctx, func(v T) { ch <- v }); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	expect(t, ch, "load", want)
+	for _, step := range steps {
+		step.Change()
+		expect(t, ch, step.Name, step.Want)
+	}
+}
+
+// Load 使用load加载一次配置并检查收到的配置为want，用于检查配置来源不可用时从本地缓存启动
+func Load[T any](t *testing.T, load func(ctx context.Context, setter func(T)) error, want T) {
+	t.Helper()
+	Watch(t, load, want, nil)
+}
+
+func expect[T any](t *testing.T, ch <-chan T, name string, want T) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	case <-time.After(Timeout):
+		t.Fatalf("%s: timeout", name)
+	}
+}
@@ -2,6 +2,7 @@ package jsonconfig
 
 import (
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"strings"
@@ -27,7 +28,20 @@ func convertToStringSlice(arr []gjson.Result) []string {
 	return ret
 }
 
-func parseJSON(fs *flag.FlagSet, prefix string, result *gjson.Result) {
+// parseJSON 设置所有key对应的参数，返回所有设置失败的错误，strict为true时不存在对应参数的key同样返回错误
+func parseJSON(fs *flag.FlagSet, prefix string, result *gjson.Result, strict bool) error {
+	var errs error
+	set := func(key, value string) {
+		if fs.Lookup(key) == nil {
+			if strict {
+				errs = errors.Join(errs, fmt.Errorf("%s: 未知的参数", key))
+			}
+			return
+		}
+		if err := fs.Set(key, value); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
 	result.ForEach(func(key, value gjson.Result) bool {
 		flagKey := prefix + key.Str
 		switch value.Type {
@@ -36,24 +50,36 @@ func parseJSON(fs *flag.FlagSet, prefix string, result *gjson.Result) {
 				var sb strings.Builder
 				wt := csv.NewWriter(&sb)
 				wt.Write(convertToStringSlice(value.Array()))
-				fs.Set(flagKey, sb.String())
+				wt.Flush()
+				set(flagKey, sb.String())
 			} else if value.IsObject() {
-				parseJSON(fs, flagKey+"-", &value)
+				errs = errors.Join(errs, parseJSON(fs, flagKey+"-", &value, strict))
 			}
 		default:
-			fs.Set(flagKey, value.String())
+			set(flagKey, value.String())
 		}
 		return true
 	})
+	return errs
 }
 
+// ParseJSON 使用json设置fs中的参数，json中不存在对应参数的key会被忽略，
+// 参数值不合法时返回错误，其他key仍然会被设置
 func ParseJSON(fs *flag.FlagSet, json string) error {
+	return parseJSONObject(fs, json, false)
+}
+
+// ParseJSONStrict 与ParseJSON一致，但json中不存在对应参数的key同样会返回错误
+func ParseJSONStrict(fs *flag.FlagSet, json string) error {
+	return parseJSONObject(fs, json, true)
+}
+
+func parseJSONObject(fs *flag.FlagSet, json string, strict bool) error {
 	result := gjson.Parse(json)
 	if !result.IsObject() {
 		return fmt.Errorf("参数解析失败")
 	}
-	parseJSON(fs, "", &result)
-	return nil
+	return parseJSON(fs, "", &result, strict)
 }
 
 func parseJSONToKeyValue(prefix string, result *gjson.Result) []box.ConfigItem {
@@ -21,7 +21,7 @@ func Test_parseJSON(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			parseJSON(tt.args.fs, tt.args.prefix, tt.args.result)
+			parseJSON(tt.args.fs, tt.args.prefix, tt.args.result, false)
 		})
 	}
 }
@@ -31,6 +31,7 @@ func TestParseJSON(t *testing.T) {
 		name     string
 		fs       *flag.FlagSet
 		json     string
+		strict   bool
 		wantErr  bool
 		wantFlag map[string]string
 	}{
@@ -47,10 +48,55 @@ func TestParseJSON(t *testing.T) {
 				"a": "c",
 			},
 		},
+		{
+			name: "unknown key",
+			fs: func() *flag.FlagSet {
+				fs := flag.NewFlagSet("unknown", flag.ContinueOnError)
+				fs.String("a", "b", "test a")
+				return fs
+			}(),
+			wantErr: false,
+			json:    `{"a":"c","b":{"c":"d"}}`,
+			wantFlag: map[string]string{
+				"a": "c",
+			},
+		},
+		{
+			name: "strict unknown key",
+			fs: func() *flag.FlagSet {
+				fs := flag.NewFlagSet("strict", flag.ContinueOnError)
+				fs.String("a", "b", "test a")
+				return fs
+			}(),
+			strict:  true,
+			wantErr: true,
+			json:    `{"a":"c","b":{"c":"d"}}`,
+			wantFlag: map[string]string{
+				"a": "c",
+			},
+		},
+		{
+			name: "invalid value",
+			fs: func() *flag.FlagSet {
+				fs := flag.NewFlagSet("invalid", flag.ContinueOnError)
+				fs.String("a", "b", "test a")
+				fs.Int("n", 1, "test n")
+				return fs
+			}(),
+			wantErr: true,
+			json:    `{"a":"c","n":"x"}`,
+			wantFlag: map[string]string{
+				"a": "c",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := ParseJSON(tt.fs, tt.json); (err != nil) != tt.wantErr {
+			parse := ParseJSON
+			if tt.strict {
+				parse = ParseJSONStrict
+			}
+			if err := parse(tt.fs, tt.json); (err != nil) != tt.wantErr {
 				t.Errorf("ParseJSON() error = %v, wantErr %v", err, tt.wantErr)
 			}
 			tt.fs.Parse(nil)
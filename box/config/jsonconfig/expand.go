@@ -0,0 +1,63 @@
+package jsonconfig
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/daemtri/di/box"
+)
+
+// ExpandEnv 替换s中的 ${VAR} 和 ${VAR:-default} 为环境变量的值，
+// ${VAR:-default} 在环境变量不存在或者为空时使用default，
+// ${VAR} 引用的环境变量不存在时返回错误，$${VAR} 会被转义为 ${VAR}
+func ExpandEnv(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var sb strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			sb.WriteString(s[:start-1])
+			sb.WriteString("${")
+			s = s[start+2:]
+			continue
+		}
+		sb.WriteString(s[:start])
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("环境变量引用未闭合: %s", s[start:])
+		}
+		expr := s[start+2 : start+end]
+		name, def, hasDef := strings.Cut(expr, ":-")
+		if name == "" {
+			return "", fmt.Errorf("环境变量引用为空: ${%s}", expr)
+		}
+		value, ok := os.LookupEnv(name)
+		switch {
+		case hasDef && value == "":
+			value = def
+		case !ok:
+			return "", fmt.Errorf("环境变量%s不存在，并且没有指定默认值", name)
+		}
+		sb.WriteString(value)
+		s = s[start+end+1:]
+	}
+}
+
+// ExpandEnvItems 对所有配置项的值执行ExpandEnv
+func ExpandEnvItems(items []box.ConfigItem) ([]box.ConfigItem, error) {
+	for i := range items {
+		value, err := ExpandEnv(items[i].Value)
+		if err != nil {
+			return nil, fmt.Errorf("配置%s解析失败: %w", items[i].Key, err)
+		}
+		items[i].Value = value
+	}
+	return items, nil
+}
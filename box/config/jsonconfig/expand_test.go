@@ -0,0 +1,36 @@
+package jsonconfig
+
+import (
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("EXPAND_HOST", "10.0.0.1")
+	t.Setenv("EXPAND_EMPTY", "")
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", in: "redis:6379", want: "redis:6379"},
+		{name: "var", in: "${EXPAND_HOST}:6379", want: "10.0.0.1:6379"},
+		{name: "default", in: "${EXPAND_MISSING:-127.0.0.1}", want: "127.0.0.1"},
+		{name: "empty with default", in: "${EXPAND_EMPTY:-127.0.0.1}", want: "127.0.0.1"},
+		{name: "empty", in: "${EXPAND_EMPTY}", want: ""},
+		{name: "escape", in: "$${EXPAND_HOST}", want: "${EXPAND_HOST}"},
+		{name: "missing", in: "${EXPAND_MISSING}", wantErr: true},
+		{name: "unterminated", in: "${EXPAND_HOST", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandEnv(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
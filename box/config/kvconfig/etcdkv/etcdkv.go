@@ -0,0 +1,110 @@
+// Package etcdkv etcd v3 键值存储，用于kvconfig配置加载器
+package etcdkv
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/kvconfig"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"golang.org/x/exp/slog"
+)
+
+func Init(opts ...box.LoaderOption) box.BuildOption {
+	return box.UseConfigLoader("etcd", &ConfigLoader{}, opts...)
+}
+
+// ConfigLoader 使用flag创建etcd客户端的kvconfig配置加载器
+type ConfigLoader struct {
+	Endpoints   []string      `flag:"endpoints" default:"127.0.0.1:2379" usage:"etcd地址，多个地址使用逗号分隔" validate:"required"`
+	Username    string        `flag:"username" default:"" usage:"etcd用户名"`
+	Password    string        `flag:"password" default:"" usage:"etcd密码" secret:"true"`
+	DialTimeout time.Duration `flag:"dial-timeout" default:"5s" usage:"etcd连接超时时间"`
+
+	kvconfig.ConfigLoader `flag:""`
+}
+
+func (c *ConfigLoader) Load(ctx context.Context, setter func([]box.ConfigItem)) error {
+	return c.LoadUpdates(ctx, func(update box.ConfigUpdate) {
+		setter(update.Items)
+	})
+}
+
+// LoadUpdates 创建etcd客户端后使用kvconfig加载配置，box优先使用LoadUpdates，
+// 所以必须覆盖内嵌的kvconfig.ConfigLoader的LoadUpdates，否则客户端不会被创建
+func (c *ConfigLoader) LoadUpdates(ctx context.Context, setter func(update box.ConfigUpdate)) error {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   c.Endpoints,
+		Username:    c.Username,
+		Password:    c.Password,
+		DialTimeout: c.DialTimeout,
+		Context:     ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("connect etcd failed: %w", err)
+	}
+	c.KV = New(client)
+	if err := c.ConfigLoader.LoadUpdates(ctx, setter); err != nil {
+		_ = client.Close()
+		return err
+	}
+	// 监听在ctx结束时停止，之后不再需要客户端
+	go func() {
+		<-ctx.Done()
+		if err := client.Close(); err != nil {
+			slog.Warn("close etcd client failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// KV 使用etcd v3客户端实现kvconfig.KV
+type KV struct {
+	client *clientv3.Client
+}
+
+func New(client *clientv3.Client) *KV {
+	return &KV{client: client}
+}
+
+func (kv *KV) Get(ctx context.Context, prefix string) ([]kvconfig.KeyValue, error) {
+	resp, err := kv.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	kvs := make([]kvconfig.KeyValue, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		kvs = append(kvs, kvconfig.KeyValue{Key: string(item.Key), Value: string(item.Value)})
+	}
+	return kvs, nil
+}
+
+func (kv *KV) Watch(ctx context.Context, prefix string) (<-chan []kvconfig.Event, error) {
+	wch := kv.client.Watch(ctx, prefix, clientv3.WithPrefix())
+	ch := make(chan []kvconfig.Event)
+	go func() {
+		defer close(ch)
+		for resp := range wch {
+			if err := resp.Err(); err != nil {
+				slog.Warn("watch etcd failed", "prefix", prefix, "error", err)
+				continue
+			}
+			events := make([]kvconfig.Event, 0, len(resp.Events))
+			for _, event := range resp.Events {
+				events = append(events, kvconfig.Event{
+					Key:     string(event.Kv.Key),
+					Value:   string(event.Kv.Value),
+					Deleted: event.Type == clientv3.EventTypeDelete,
+				})
+			}
+			select {
+			case ch <- events:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
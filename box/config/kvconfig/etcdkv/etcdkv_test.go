@@ -0,0 +1,29 @@
+package etcdkv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/daemtri/di/box"
+)
+
+// TestLoadUpdatesCreatesClient box优先通过UpdatingConfigLoader加载配置，该路径同样需要创建etcd客户端
+func TestLoadUpdatesCreatesClient(t *testing.T) {
+	var loader box.ConfigLoader = &ConfigLoader{
+		Endpoints:   []string{"127.0.0.1:1"},
+		DialTimeout: time.Second,
+	}
+	updating, ok := loader.(box.UpdatingConfigLoader)
+	if !ok {
+		t.Fatal("ConfigLoader should implement box.UpdatingConfigLoader")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := updating.LoadUpdates(ctx, func(box.ConfigUpdate) {}); err == nil {
+		t.Error("LoadUpdates() expected error for unreachable etcd")
+	}
+	if loader.(*ConfigLoader).KV == nil {
+		t.Error("LoadUpdates() did not create the etcd client")
+	}
+}
@@ -0,0 +1,106 @@
+// Package kvconfig 键值存储配置加载器，prefix下的每个key对应一个配置项，
+// 如prefix为/services/user/时，/services/user/redis/addr对应的配置项为redis-addr
+package kvconfig
+
+import (
+	"context"
+	"strings"
+
+	"github.com/daemtri/di/box"
+)
+
+// KeyValue 键值对
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Event 监听到的键值变更，Deleted为true时表示key被删除
+type Event struct {
+	Key     string
+	Value   string
+	Deleted bool
+}
+
+// KV defined a key/value store the config loader reads from
+type KV interface {
+	// Get 返回prefix下的所有键值对
+	Get(ctx context.Context, prefix string) ([]KeyValue, error)
+	// Watch 监听prefix下的键值变更，返回的channel在ctx结束时关闭
+	Watch(ctx context.Context, prefix string) (<-chan []Event, error)
+}
+
+func Init(kv KV, opts ...box.LoaderOption) box.BuildOption {
+	return box.UseConfigLoader("kv", NewConfigLoader(kv), opts...)
+}
+
+type ConfigLoader struct {
+	Prefix string `flag:"prefix" default:"" usage:"配置项key的前缀，如/services/user/" validate:"required"`
+	Watch  bool   `flag:"watch" default:"false" usage:"是否监听prefix下的键值变更，变更的配置项会被重新设置"`
+
+	// KV 键值存储，需要在Load之前设置
+	KV KV
+}
+
+func NewConfigLoader(kv KV) *ConfigLoader {
+	return &ConfigLoader{KV: kv}
+}
+
+func (c *ConfigLoader) Load(ctx context.Context, setter func([]box.ConfigItem)) error {
+	return c.LoadUpdates(ctx, func(update box.ConfigUpdate) {
+		setter(update.Items)
+	})
+}
+
+// LoadUpdates 加载prefix下的所有键值，Watch为true时监听变更，被删除的key会通过update.Removed报告
+func (c *ConfigLoader) LoadUpdates(ctx context.Context, setter func(update box.ConfigUpdate)) error {
+	// 先开始监听再读取，避免丢失读取过程中发生的变更
+	var events <-chan []Event
+	if c.Watch {
+		var err error
+		if events, err = c.KV.Watch(ctx, c.Prefix); err != nil {
+			return err
+		}
+	}
+	kvs, err := c.KV.Get(ctx, c.Prefix)
+	if err != nil {
+		return err
+	}
+	items := make([]box.ConfigItem, 0, len(kvs))
+	for _, kv := range kvs {
+		if key := c.flagKey(kv.Key); key != "" {
+			items = append(items, box.ConfigItem{Key: key, Value: kv.Value})
+		}
+	}
+	setter(box.ConfigUpdate{Items: items})
+	if events != nil {
+		go c.watch(events, setter)
+	}
+	return nil
+}
+
+func (c *ConfigLoader) watch(events <-chan []Event, setter func(update box.ConfigUpdate)) {
+	for batch := range events {
+		var update box.ConfigUpdate
+		for _, event := range batch {
+			key := c.flagKey(event.Key)
+			if key == "" {
+				continue
+			}
+			if event.Deleted {
+				update.Removed = append(update.Removed, key)
+				continue
+			}
+			update.Items = append(update.Items, box.ConfigItem{Key: key, Value: event.Value})
+		}
+		if len(update.Items) > 0 || len(update.Removed) > 0 {
+			setter(update)
+		}
+	}
+}
+
+// flagKey 去除prefix之后将/替换为-，key为prefix本身时返回空字符串
+func (c *ConfigLoader) flagKey(key string) string {
+	key = strings.Trim(strings.TrimPrefix(key, c.Prefix), "/")
+	return strings.ReplaceAll(key, "/", "-")
+}
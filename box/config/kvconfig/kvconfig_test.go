@@ -0,0 +1,52 @@
+package kvconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/internal/loadertest"
+)
+
+func TestLoadAndWatch(t *testing.T) {
+	kv := NewMemoryKV()
+	kv.Put("/services/user/redis/addr", "a")
+	kv.Put("/services/user/redis/db", "1")
+	kv.Put("/services/order/redis/addr", "b")
+
+	c := &ConfigLoader{Prefix: "/services/user/", Watch: true, KV: kv}
+	loadertest.Watch(t, c.LoadUpdates, box.ConfigUpdate{Items: []box.ConfigItem{{Key: "redis-addr", Value: "a"}, {Key: "redis-db", Value: "1"}}}, []loadertest.Step[box.ConfigUpdate]{
+		{
+			Name: "removed",
+			Change: func() {
+				kv.Put("/services/order/redis/addr", "c")
+				kv.Delete("/services/user/redis/db")
+			},
+			Want: box.ConfigUpdate{Removed: []string{"redis-db"}},
+		},
+		{
+			Name:   "changed",
+			Change: func() { kv.Put("/services/user/redis/addr", "d") },
+			Want:   box.ConfigUpdate{Items: []box.ConfigItem{{Key: "redis-addr", Value: "d"}}},
+		},
+	})
+}
+
+func TestMemoryKVWatchClosed(t *testing.T) {
+	kv := NewMemoryKV()
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := kv.Watch(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Watch() channel expected to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Watch() channel not closed after ctx done")
+	}
+}
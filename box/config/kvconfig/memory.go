@@ -0,0 +1,86 @@
+package kvconfig
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryKV 内存键值存储，用于测试以及不依赖外部存储的场景
+type MemoryKV struct {
+	mux      sync.Mutex
+	data     map[string]string
+	watchers map[*memoryWatcher]struct{}
+}
+
+type memoryWatcher struct {
+	ctx    context.Context
+	prefix string
+	ch     chan []Event
+}
+
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{
+		data:     make(map[string]string),
+		watchers: make(map[*memoryWatcher]struct{}),
+	}
+}
+
+func (m *MemoryKV) Put(key, value string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.data[key] = value
+	m.notify(Event{Key: key, Value: value})
+}
+
+func (m *MemoryKV) Delete(key string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.data[key]; !ok {
+		return
+	}
+	delete(m.data, key)
+	m.notify(Event{Key: key, Deleted: true})
+}
+
+// notify 必须在持有锁时调用，保证事件的顺序以及不会向已关闭的channel发送
+func (m *MemoryKV) notify(event Event) {
+	for w := range m.watchers {
+		if !strings.HasPrefix(event.Key, w.prefix) {
+			continue
+		}
+		select {
+		case w.ch <- []Event{event}:
+		case <-w.ctx.Done():
+		}
+	}
+}
+
+func (m *MemoryKV) Get(ctx context.Context, prefix string) ([]KeyValue, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	kvs := make([]KeyValue, 0, len(m.data))
+	for key, value := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, KeyValue{Key: key, Value: value})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs, nil
+}
+
+func (m *MemoryKV) Watch(ctx context.Context, prefix string) (<-chan []Event, error) {
+	w := &memoryWatcher{ctx: ctx, prefix: prefix, ch: make(chan []Event, 16)}
+	m.mux.Lock()
+	m.watchers[w] = struct{}{}
+	m.mux.Unlock()
+	go func() {
+		<-ctx.Done()
+		m.mux.Lock()
+		defer m.mux.Unlock()
+		delete(m.watchers, w)
+		close(w.ch)
+	}()
+	return w.ch, nil
+}
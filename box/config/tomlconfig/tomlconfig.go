@@ -0,0 +1,91 @@
+package tomlconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/jsonconfig"
+)
+
+func Init(opts ...box.LoaderOption) box.BuildOption {
+	return box.UseConfigLoader("", &ConfigLoader{}, opts...)
+}
+
+type ConfigLoader struct {
+	Configfile []string `flag:"config" default:"./config.toml" usage:"配置文件路径，多个文件使用逗号分隔，越靠后的文件优先级越高"`
+	ExpandEnv  bool     `flag:"config-expand-env" default:"true" usage:"是否替换配置文件中的${VAR}和${VAR:-default}为环境变量的值"`
+}
+
+// Load 加载所有配置文件，并按优先级合并成一层配置
+func (c *ConfigLoader) Load(ctx context.Context, setter func([]box.ConfigItem)) error {
+	layers, err := c.loadLayers()
+	if err != nil {
+		return err
+	}
+	items := make([]box.ConfigItem, 0)
+	for i := range layers {
+		items = append(items, layers[i].Items...)
+	}
+	setter(items)
+	return nil
+}
+
+// LoadLayers 加载所有配置文件，每个配置文件作为一层配置
+func (c *ConfigLoader) LoadLayers(ctx context.Context, setter func([]box.ConfigLayer)) error {
+	layers, err := c.loadLayers()
+	if err != nil {
+		return err
+	}
+	setter(layers)
+	return nil
+}
+
+func (c *ConfigLoader) loadLayers() ([]box.ConfigLayer, error) {
+	layers := make([]box.ConfigLayer, 0, len(c.Configfile))
+	for _, file := range c.Configfile {
+		items, err := Load(file)
+		if err != nil {
+			return nil, err
+		}
+		if c.ExpandEnv {
+			if items, err = jsonconfig.ExpandEnvItems(items); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+		layers = append(layers, box.ConfigLayer{Name: file, Items: items})
+	}
+	return layers, nil
+}
+
+// Load 读取toml配置文件，嵌套的table使用-连接成key，数组转换为csv格式
+func Load(configFile string) ([]box.ConfigItem, error) {
+	tomlRawConfig, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("配置文件读取失败: %w", err)
+	}
+	return Parse(tomlRawConfig)
+}
+
+// Parse 解析toml内容为配置项
+func Parse(tomlRawConfig []byte) ([]box.ConfigItem, error) {
+	if len(tomlRawConfig) == 0 {
+		return nil, nil
+	}
+	var config map[string]any
+	if err := toml.Unmarshal(tomlRawConfig, &config); err != nil {
+		return nil, fmt.Errorf("配置文件解析失败: %w", err)
+	}
+	jsonRawConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("配置文件解析失败: %w", err)
+	}
+	items, err := jsonconfig.ParseJSONToKeyValue(string(jsonRawConfig))
+	if err != nil {
+		return nil, fmt.Errorf("配置文件解析失败: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,46 @@
+package tomlconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	items, err := Parse([]byte(`
+addr = ":8080"
+debug = true
+
+[redis]
+addr = "127.0.0.1:6379"
+db = 1
+hosts = ["a", "b,c"]
+
+[redis.pool]
+size = 10
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string, len(items))
+	for _, item := range items {
+		got[item.Key] = item.Value
+	}
+	want := map[string]string{
+		"addr":            ":8080",
+		"debug":           "true",
+		"redis-addr":      "127.0.0.1:6379",
+		"redis-db":        "1",
+		"redis-hosts":     "a,\"b,c\"\n",
+		"redis-pool-size": "10",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+
+	if _, err := Parse([]byte("addr = ")); err == nil {
+		t.Error("Parse() invalid toml expected error")
+	}
+	if items, err := Parse(nil); err != nil || items != nil {
+		t.Errorf("Parse(nil) = %v, %v", items, err)
+	}
+}
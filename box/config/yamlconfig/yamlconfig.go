@@ -4,29 +4,128 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/daemtri/di/box"
 	"github.com/daemtri/di/box/config/jsonconfig"
+	"golang.org/x/exp/slog"
 	"sigs.k8s.io/yaml"
 )
 
-func Init() box.BuildOption {
-	return box.UseConfigLoader("", &ConfigLoader{})
+func Init(opts ...box.LoaderOption) box.BuildOption {
+	return box.UseConfigLoader("", &ConfigLoader{}, opts...)
 }
 
 type ConfigLoader struct {
-	Configfile string `flag:"config" default:"./config.yaml" usage:"配置文件路径"`
+	Configfile []string `flag:"config" default:"./config.yaml" usage:"配置文件路径，多个文件使用逗号分隔，越靠后的文件优先级越高"`
+	Profile    string   `flag:"profile" default:"" usage:"环境名称，指定后会在每个配置文件之后加载同目录下的 <文件名>.<profile>.<扩展名>，如config.prod.yaml"`
+	ExpandEnv  bool     `flag:"config-expand-env" default:"true" usage:"是否替换配置文件中的${VAR}和${VAR:-default}为环境变量的值"`
+
+	Watch         bool          `flag:"config-watch" default:"false" usage:"是否监听配置文件变更，变更的配置项会被重新设置"`
+	WatchInterval time.Duration `flag:"config-watch-interval" default:"5s" usage:"监听配置文件变更的轮询间隔" validate:"gt=0"`
 }
 
+// Load 加载所有配置文件，并按优先级合并成一层配置
 func (c *ConfigLoader) Load(ctx context.Context, setter func([]box.ConfigItem)) error {
-	items, err := Load(c.Configfile)
+	layers, err := c.loadLayers()
 	if err != nil {
 		return err
 	}
+	items := make([]box.ConfigItem, 0)
+	for i := range layers {
+		items = append(items, layers[i].Items...)
+	}
 	setter(items)
 	return nil
 }
 
+// LoadLayers 加载所有配置文件，每个配置文件作为一层配置
+func (c *ConfigLoader) LoadLayers(ctx context.Context, setter func([]box.ConfigLayer)) error {
+	layers, err := c.loadLayers()
+	if err != nil {
+		return err
+	}
+	setter(layers)
+	if c.Watch {
+		go c.watch(ctx, layers, setter)
+	}
+	return nil
+}
+
+// watch 定时重新加载配置文件，只设置发生变化以及被删除的配置项，直到ctx结束
+// 每次都重新读取文件，所以Kubernetes ConfigMap通过替换符号链接更新文件的方式同样适用
+func (c *ConfigLoader) watch(ctx context.Context, last []box.ConfigLayer, setter func([]box.ConfigLayer)) {
+	ticker := time.NewTicker(c.WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current, err := c.loadLayers()
+		if err != nil {
+			slog.Warn("reload config file failed", "error", err)
+			continue
+		}
+		changedLayers := make([]box.ConfigLayer, len(current))
+		hasChanged := false
+		for i := range current {
+			changed, removed := box.ChangedConfigItems(last[i].Items, current[i].Items)
+			changedLayers[i] = box.ConfigLayer{Name: current[i].Name, Items: changed, Removed: removed}
+			hasChanged = hasChanged || len(changed) > 0 || len(removed) > 0
+		}
+		last = current
+		if hasChanged {
+			setter(changedLayers)
+		}
+	}
+}
+
+// files 返回按优先级从低到高排序的所有配置文件，以及每个文件是否必须存在
+func (c *ConfigLoader) files() (files []string, required []bool) {
+	for _, file := range c.Configfile {
+		files = append(files, file)
+		required = append(required, true)
+		if c.Profile != "" {
+			files = append(files, ProfileFile(file, c.Profile))
+			required = append(required, false)
+		}
+	}
+	return files, required
+}
+
+func (c *ConfigLoader) loadLayers() ([]box.ConfigLayer, error) {
+	files, required := c.files()
+	layers := make([]box.ConfigLayer, 0, len(files))
+	for i, file := range files {
+		items, err := Load(file)
+		if err != nil {
+			if !required[i] && os.IsNotExist(err) {
+				// 保留空的一层，使得每个文件对应的层级保持不变
+				layers = append(layers, box.ConfigLayer{Name: file})
+				continue
+			}
+			return nil, err
+		}
+		if c.ExpandEnv {
+			if items, err = jsonconfig.ExpandEnvItems(items); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+		layers = append(layers, box.ConfigLayer{Name: file, Items: items})
+	}
+	return layers, nil
+}
+
+// ProfileFile 返回配置文件对应profile的文件路径，如 config.yaml 对应 prod 的文件为 config.prod.yaml
+func ProfileFile(file string, profile string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "." + profile + ext
+}
+
 func Load(configFile string) ([]box.ConfigItem, error) {
 	yamlRawConfig, err := os.ReadFile(configFile)
 	if err != nil {
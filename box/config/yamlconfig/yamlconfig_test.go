@@ -0,0 +1,52 @@
+package yamlconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daemtri/di/box"
+	"github.com/daemtri/di/box/config/internal/loadertest"
+)
+
+// writeFile 先写入临时文件再重命名，避免监听时读取到写入了一半的文件
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.WriteFile(file+".tmp", []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(file+".tmp", file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	profileFile := filepath.Join(dir, "config.prod.yaml")
+	writeFile(t, file, "redis:\n  addr: a\n  db: 1\n")
+
+	c := &ConfigLoader{Configfile: []string{file}, Profile: "prod", Watch: true, WatchInterval: 10 * time.Millisecond}
+	want := []box.ConfigLayer{
+		{Name: file, Items: []box.ConfigItem{{Key: "redis-addr", Value: "a"}, {Key: "redis-db", Value: "1"}}},
+		{Name: profileFile},
+	}
+	loadertest.Watch(t, c.LoadLayers, want, []loadertest.Step[[]box.ConfigLayer]{
+		{
+			Name:   "profile added",
+			Change: func() { writeFile(t, profileFile, "redis:\n  db: 2\n") },
+			Want:   []box.ConfigLayer{{Name: file}, {Name: profileFile, Items: []box.ConfigItem{{Key: "redis-db", Value: "2"}}}},
+		},
+		{
+			Name:   "removed",
+			Change: func() { writeFile(t, file, "redis:\n  addr: a\n") },
+			Want:   []box.ConfigLayer{{Name: file, Removed: []string{"redis-db"}}, {Name: profileFile}},
+		},
+		{
+			Name:   "profile removed",
+			Change: func() { _ = os.Remove(profileFile) },
+			Want:   []box.ConfigLayer{{Name: file}, {Name: profileFile, Removed: []string{"redis-db"}}},
+		},
+	})
+}
@@ -1,12 +1,17 @@
 package box
 
 import (
+	"encoding/json"
 	"flag"
-	"github.com/tidwall/sjson"
+	"fmt"
 	"io"
 	"reflect"
-	"sigs.k8s.io/yaml"
 	"strings"
+
+	"github.com/daemtri/di/box/flagvar"
+	"github.com/daemtri/di/box/flagx"
+	"github.com/tidwall/sjson"
+	"sigs.k8s.io/yaml"
 )
 
 type getter interface {
@@ -23,16 +28,22 @@ func EncodeFlags(w io.Writer) (err error) {
 	jsonValue := `{}`
 
 	nfs.VisitAll(func(p string, f *flag.Flag) {
-		if p == "" && (f.Name == "config" || f.Name == "print-config") {
+		if isBuiltinFlag(p, f) {
 			return
 		}
 		strValue := f.Value.String()
 		if strValue == "" {
 			return
 		}
+		fullName := f.Name
+		if p != "" {
+			fullName = p + "-" + f.Name
+		}
 		var value any = strValue
 		valueGetter, ok := f.Value.(flag.Getter)
-		if ok {
+		if nfs.IsSecret(fullName) {
+			value = flagvar.Redacted
+		} else if ok {
 			anyValue := valueGetter.Get()
 			gt, ok := anyValue.(getter)
 			if ok {
@@ -41,10 +52,8 @@ func EncodeFlags(w io.Writer) (err error) {
 				value = anyValue
 			}
 		}
-		key := strings.ReplaceAll(f.Name, "-", ".")
-		if p != "" {
-			key = p + "." + key
-		}
+		// 与EncodeSchema以及EncodeConfigTemplate一致，前缀同样按-拆分
+		key := strings.ReplaceAll(fullName, "-", ".")
 
 		jsonValue, err = sjson.Set(jsonValue, key, value)
 		if err != nil {
@@ -55,3 +64,161 @@ func EncodeFlags(w io.Writer) (err error) {
 	_, err = w.Write(yamlValue)
 	return
 }
+
+// isBuiltinFlag 判断是否为配置文件加载器（包括--profile）及打印配置相关的参数，这些参数不需要保存到配置文件中
+func isBuiltinFlag(p string, f *flag.Flag) bool {
+	return p == "" && (f.Name == "config" || strings.HasPrefix(f.Name, "config-") || f.Name == "profile" ||
+		f.Name == "print-config" || f.Name == "generate-config")
+}
+
+// annotatedFlag 记录了参数的值、默认值、来源以及被覆盖的值
+type annotatedFlag struct {
+	Value    string           `json:"value"`
+	Default  string           `json:"default"`
+	Source   string           `json:"source"`
+	Shadowed []annotatedValue `json:"shadowed,omitempty"`
+}
+
+type annotatedValue struct {
+	Source string `json:"source"`
+	Value  string `json:"value"`
+}
+
+// EncodeAnnotatedFlags 保存所有参数的当前值、默认值、当前值的来源以及被更高优先级来源覆盖的值
+// format: yaml，key为参数的完整名称
+func EncodeAnnotatedFlags(w io.Writer) error {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	flags := make(map[string]annotatedFlag)
+	nfs.VisitAll(func(p string, f *flag.Flag) {
+		if isBuiltinFlag(p, f) {
+			return
+		}
+		key := f.Name
+		if p != "" {
+			key = p + "-" + f.Name
+		}
+		pv, ok := nfs.Provenance(key)
+		if !ok {
+			return
+		}
+		af := annotatedFlag{
+			Value:   nfs.Redact(key, pv.Value),
+			Default: nfs.Redact(key, pv.Default),
+			Source:  "default",
+		}
+		if pv.Source != nil {
+			af.Source = pv.Source.String()
+		}
+		for _, sv := range pv.Shadowed {
+			af.Shadowed = append(af.Shadowed, annotatedValue{Source: sv.Source.String(), Value: nfs.Redact(key, sv.Value)})
+		}
+		flags[key] = af
+	})
+	data, err := yaml.Marshal(flags)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// EncodeConfigTemplate 输出包含所有参数的yaml配置文件模板，与EncodeFlags以及EncodeSchema一致，参数名称按-拆分后嵌套，
+// 每个参数的值为默认值，注释中包含用法说明、是否必须、validate规则以及对应的环境变量，
+// 敏感参数以及必须参数没有默认值时，非字符串参数会被注释
+func EncodeConfigTemplate(w io.Writer) error {
+	var sb strings.Builder
+	root := newFlagTree(describeFlags())
+	for i, name := range root.sortedChildren() {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		writeTemplateNode(&sb, "", name, root.children[name])
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeTemplateNode 输出节点及其子节点，节点同时是参数时，子节点使用-连接的名称输出在同一层，
+// 如参数db与db-addr输出为 db 和 db-addr，加载器会将两者都还原为完整的参数名称
+func writeTemplateNode(sb *strings.Builder, indent string, name string, n *schemaNode) {
+	if n.flag != nil {
+		writeTemplateFlag(sb, indent, name, *n.flag)
+		for _, child := range n.sortedChildren() {
+			writeTemplateNode(sb, indent, name+"-"+child, n.children[child])
+		}
+		return
+	}
+	// 所有子参数都被注释时，父节点也需要注释，否则会输出值为null的节点
+	if n.commented() {
+		fmt.Fprintf(sb, "%s# %s:\n", indent, name)
+	} else {
+		fmt.Fprintf(sb, "%s%s:\n", indent, name)
+	}
+	for _, child := range n.sortedChildren() {
+		writeTemplateNode(sb, indent+"  ", child, n.children[child])
+	}
+}
+
+func writeTemplateFlag(sb *strings.Builder, indent string, name string, fd flagDescription) {
+	if fd.Usage != "" {
+		for _, line := range strings.Split(fd.Usage, "\n") {
+			fmt.Fprintf(sb, "%s# %s\n", indent, line)
+		}
+	}
+	notes := make([]string, 0, 4)
+	if fd.Required {
+		notes = append(notes, "required")
+	}
+	if fd.Secret {
+		notes = append(notes, "secret")
+	}
+	if fd.Validate != "" {
+		notes = append(notes, "validate: "+fd.Validate)
+	}
+	notes = append(notes, "env: "+flagx.EnvKey(envPrefix, fd.Key))
+	fmt.Fprintf(sb, "%s# %s\n", indent, strings.Join(notes, " | "))
+
+	if value, ok := fd.templateValue(); ok {
+		fmt.Fprintf(sb, "%s%s: %s\n", indent, name, value)
+	} else {
+		fmt.Fprintf(sb, "%s# %s:\n", indent, name)
+	}
+}
+
+// templateValue 返回参数在配置模板中的值，没有默认值的非字符串必须参数以及敏感参数返回false
+func (fd flagDescription) templateValue() (string, bool) {
+	if def, ok := fd.defaultValue(); ok {
+		// json是yaml的子集，使用json格式输出值可以保证字符串被正确转义
+		data, err := json.Marshal(def)
+		if err == nil {
+			return string(data), true
+		}
+	}
+	switch {
+	case fd.Type == "string":
+		return `""`, true
+	case fd.Type == "array":
+		return "[]", true
+	case !fd.Required && !fd.Secret:
+		// 没有默认值的可选参数使用零值
+		return map[string]string{"boolean": "false", "integer": "0", "number": "0"}[fd.Type], true
+	}
+	return "", false
+}
+
+// commented 判断节点下的参数是否都会在配置模板中被注释
+func (n *schemaNode) commented() bool {
+	if n.flag != nil {
+		if _, ok := n.flag.templateValue(); ok {
+			return false
+		}
+	}
+	for _, child := range n.children {
+		if !child.commented() {
+			return false
+		}
+	}
+	return true
+}
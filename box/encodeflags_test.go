@@ -0,0 +1,220 @@
+package box
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/daemtri/di/box/flagvar"
+	"golang.org/x/exp/slog"
+	"sigs.k8s.io/yaml"
+)
+
+type annotatedTest struct{}
+
+func TestEncodeAnnotatedFlags(t *testing.T) {
+	resetFlags(t)
+	Provide[*txTestServer[annotatedTest]](&txTestServer[annotatedTest]{}, WithFlags("annotated"))
+	bindFlags(t, "--annotated-host=cli")
+
+	source := testSource(t)
+	if err := SetConfig([]ConfigItem{
+		{Key: "annotated-host", Value: "file"},
+		{Key: "annotated-port", Value: "81"},
+	}, source); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeAnnotatedFlags(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]annotatedFlag
+	if err := yaml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal %s: %v", buf.String(), err)
+	}
+	want := map[string]annotatedFlag{
+		"annotated-host": {
+			Value:    "cli",
+			Default:  "localhost",
+			Source:   "[0]args",
+			Shadowed: []annotatedValue{{Source: source.String(), Value: "file"}},
+		},
+		"annotated-port":   {Value: "81", Default: "80", Source: source.String()},
+		"annotated-tags":   {Value: "[a,b]", Default: "[a,b]", Source: "default"},
+		"annotated-labels": {Value: "[k=v]", Default: "[k=v]", Source: "default"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeAnnotatedFlags() = %s", buf.String())
+	}
+}
+
+type secretTestServer struct {
+	User     string         `flag:"user" default:"admin"`
+	Password string         `flag:"password" default:"default-pw" secret:"true"`
+	Token    flagvar.Secret `flag:"token"`
+}
+
+func (s *secretTestServer) Build(ctx context.Context) (*secretTestServer, error) {
+	return s, nil
+}
+
+func TestSecretRedaction(t *testing.T) {
+	resetFlags(t)
+	Provide[*secretTestServer](&secretTestServer{}, WithFlags("secret"))
+	fs := bindFlags(t, "--secret-token=cli-token")
+	secrets := []string{"default-pw", "file-pw", "cli-token", "file-token"}
+
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs)))
+	defer slog.SetDefault(defaultLogger)
+
+	source := testSource(t)
+	if err := SetConfig([]ConfigItem{
+		{Key: "secret-password", Value: "file-pw"},
+		{Key: "secret-token", Value: "file-token"},
+	}, source); err != nil {
+		t.Fatal(err)
+	}
+	err := SetConfig([]ConfigItem{{Key: "secret-password", Value: "env://BOX_SECRET_TEST_MISSING"}}, source)
+	var rejected *ConfigRejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("SetConfig error = %v, want *ConfigRejectedError", err)
+	}
+
+	outputs := map[string]*bytes.Buffer{"logs": &logs, "rejected": bytes.NewBufferString(rejected.Error())}
+	for name, encode := range map[string]func(w io.Writer) error{
+		"print-config":           EncodeFlags,
+		"print-config=annotated": EncodeAnnotatedFlags,
+		"usage": func(w io.Writer) error {
+			fs.SetOutput(w)
+			fs.PrintDefaults()
+			return nil
+		},
+		"snapshot": func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "%+v", Snapshot())
+			return err
+		},
+	} {
+		outputs[name] = &bytes.Buffer{}
+		if err := encode(outputs[name]); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	for name, output := range outputs {
+		for _, secret := range secrets {
+			if strings.Contains(output.String(), secret) {
+				t.Errorf("%s leaks %s: %s", name, secret, output)
+			}
+		}
+	}
+	if !strings.Contains(outputs["print-config"].String(), flagvar.Redacted) {
+		t.Errorf("print-config does not contain redacted values: %s", outputs["print-config"])
+	}
+}
+
+func TestIsBuiltinFlag(t *testing.T) {
+	tests := []struct {
+		prefix string
+		name   string
+		want   bool
+	}{
+		{"", "config", true},
+		{"", "config-watch-interval", true},
+		{"", "profile", true},
+		{"", "print-config", true},
+		{"", "generate-config", true},
+		{"", "addr", false},
+		{"redis", "profile", false},
+		{"redis", "config", false},
+	}
+	for _, tt := range tests {
+		if got := isBuiltinFlag(tt.prefix, &flag.Flag{Name: tt.name}); got != tt.want {
+			t.Errorf("isBuiltinFlag(%q, %q) = %v, want %v", tt.prefix, tt.name, got, tt.want)
+		}
+	}
+}
+
+// templateTestGolden 参数按-拆分后嵌套，敏感参数不输出默认值，没有默认值的非字符串必须参数以及敏感参数被注释掉
+const templateTestGolden = `template:
+  db:
+    # 数据库地址
+    # required | validate: required | env: GF_TEMPLATE_DB_ADDR
+    addr: ""
+    # max:
+      # required | validate: required | env: GF_TEMPLATE_DB_MAX_IDLE
+      # idle:
+  # env: GF_TEMPLATE_DEBUG
+  debug: false
+  # 服务地址
+  # 支持域名或者IP
+  # env: GF_TEMPLATE_HOST
+  host: "localhost"
+  # env: GF_TEMPLATE_LABELS
+  labels: []
+  # validate: min=1 | env: GF_TEMPLATE_PORT
+  port: 80
+  # env: GF_TEMPLATE_QUOTE
+  quote: "say \"hi\": ok"
+  # secret | env: GF_TEMPLATE_RETRIES
+  # retries:
+  # env: GF_TEMPLATE_TAGS
+  tags: ["a","b"]
+  # secret | validate: required | env: GF_TEMPLATE_TOKEN
+  token: ""
+`
+
+type templateTestOptions struct {
+	Host    string            `flag:"host" default:"localhost" usage:"服务地址\n支持域名或者IP"`
+	Port    int               `flag:"port" default:"80" validate:"min=1"`
+	Debug   bool              `flag:"debug" default:"false"`
+	Tags    []string          `flag:"tags" default:"a,b"`
+	Labels  map[string]string `flag:"labels" default:""`
+	Quote   string            `flag:"quote" default:"say \"hi\": ok"`
+	Token   string            `flag:"token" default:"default-token" secret:"true" validate:"required"`
+	Retries int               `flag:"retries" default:"3" secret:"true"`
+}
+
+func (o *templateTestOptions) Build(ctx context.Context) (*templateTestOptions, error) {
+	return o, nil
+}
+
+type templateTestDB struct {
+	Addr    string `flag:"addr" default:"" usage:"数据库地址" validate:"required"`
+	MaxIdle int    `flag:"max-idle" default:"0" validate:"required"`
+}
+
+func (o *templateTestDB) Build(ctx context.Context) (*templateTestDB, error) {
+	return o, nil
+}
+
+func TestEncodeConfigTemplate(t *testing.T) {
+	resetFlags(t)
+	Provide[*templateTestOptions](&templateTestOptions{}, WithFlags("template"))
+	Provide[*templateTestDB](&templateTestDB{}, WithFlags("template-db"))
+	bindFlags(t)
+
+	var buf bytes.Buffer
+	if err := EncodeConfigTemplate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != templateTestGolden {
+		t.Errorf("EncodeConfigTemplate() =\n%s\nwant\n%s", got, templateTestGolden)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("template is not valid yaml: %v", err)
+	}
+	// 带-的前缀与EncodeSchema一样拆分嵌套，保证模板满足schema中的required
+	db, _ := doc["template"].(map[string]any)["db"].(map[string]any)
+	if _, ok := db["addr"]; !ok {
+		t.Errorf("template.db.addr not found in %v", doc)
+	}
+}
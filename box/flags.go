@@ -21,6 +21,9 @@ func init() {
 	di.RegisterFlagBinder(reflect.TypeOf(int32(1)), bindBaseFlag[int32])
 	di.RegisterFlagBinder(reflect.TypeOf(float32(1)), bindBaseFlag[float32])
 
+	// 支持敏感参数
+	di.RegisterFlagBinder(reflect.TypeOf(flagvar.Secret("")), bindSecretFlag)
+
 	// 支持切片类型
 	di.RegisterFlagBinder(reflect.TypeOf([]uint{}), bindSliceFlag[uint])
 	di.RegisterFlagBinder(reflect.TypeOf([]uint8{}), bindSliceFlag[uint8])
@@ -69,6 +72,16 @@ func bindBaseFlag[T flagvar.BaseType](fs *flag.FlagSet, value reflect.Value, nam
 	return nil
 }
 
+func bindSecretFlag(fs *flag.FlagSet, value reflect.Value, name, def, usage string) error {
+	ptr := (*flagvar.Secret)(value.Addr().UnsafePointer())
+	defValue := *ptr
+	if value.IsZero() && def != "" {
+		defValue = flagvar.Secret(def)
+	}
+	fs.Var(flagvar.SecretVar(ptr, defValue), name, usage)
+	return nil
+}
+
 func bindSliceFlag[T flagvar.BaseType](fs *flag.FlagSet, value reflect.Value, name, def, usage string) error {
 	ptr := (*[]T)(value.Addr().UnsafePointer())
 	defValue := *ptr
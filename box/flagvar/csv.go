@@ -17,6 +17,14 @@ func readAsCSV(val string) ([]string, error) {
 	return csvReader.Read()
 }
 
+// trimBrackets 去掉String方法输出时添加的方括号，使得String的结果可以被重新设置
+func trimBrackets(val string) string {
+	if strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]") {
+		return val[1 : len(val)-1]
+	}
+	return val
+}
+
 func writeAsCSV(vals []string) (string, error) {
 	b := &bytes.Buffer{}
 	w := csv.NewWriter(b)
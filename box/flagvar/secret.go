@@ -0,0 +1,52 @@
+package flagvar
+
+// Redacted 敏感参数在输出时被替换成的值
+const Redacted = "******"
+
+// Secret 敏感字符串，如密码、密钥等，格式化输出时会被脱敏，使用Value方法获取真实的值
+type Secret string
+
+// String 返回脱敏后的值，避免在日志中泄露
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// Value 返回真实的值
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// SecretValue 敏感参数，String返回真实的值，由使用方根据IsSecret进行脱敏
+type SecretValue struct {
+	value *Secret
+}
+
+func SecretVar(p *Secret, def Secret) *SecretValue {
+	sv := &SecretValue{value: p}
+	*sv.value = def
+	return sv
+}
+
+func (s *SecretValue) Set(val string) error {
+	*s.value = Secret(val)
+	return nil
+}
+
+func (s *SecretValue) String() string {
+	if s == nil || s.value == nil {
+		return ""
+	}
+	return string(*s.value)
+}
+
+func (s *SecretValue) Get() any {
+	return string(*s.value)
+}
+
+// IsSecret 标记参数为敏感参数
+func (s *SecretValue) IsSecret() bool {
+	return true
+}
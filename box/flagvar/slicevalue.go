@@ -57,6 +57,21 @@ func (s *SliceValue[T]) Set(val string) error {
 	return nil
 }
 
+// Reset 使用val替换已有的值，而不是追加，val可以是String方法的输出
+func (s *SliceValue[T]) Reset(val string) error {
+	v, err := readAsCSV(trimBrackets(val))
+	if err != nil {
+		return err
+	}
+	iv, err := StringSliceTo[T](v)
+	if err != nil {
+		return err
+	}
+	*s.value = iv
+	s.changed = true
+	return nil
+}
+
 func (s *SliceValue[T]) String() string {
 	// flag包判断isZeroValue时会通过反射创建stringSliceValue然后调用String方法
 	if s == nil || s.value == nil {
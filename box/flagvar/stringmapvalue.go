@@ -92,3 +92,18 @@ func (s *StringMapValue[T]) Set(val string) error {
 	s.changed = true
 	return nil
 }
+
+// Reset 使用val替换已有的值，而不是合并，val可以是String方法的输出
+func (s *StringMapValue[T]) Reset(val string) error {
+	v, err := readAsCSV(trimBrackets(val))
+	if err != nil {
+		return err
+	}
+	iv, err := StringStringMapTo[T](StringSliceToStringStringMap(v))
+	if err != nil {
+		return err
+	}
+	*s.value = iv
+	s.changed = true
+	return nil
+}
@@ -1,12 +1,31 @@
 package flagx
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/daemtri/di/box/flagvar"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	// ErrUnknownKey 参数不存在
+	ErrUnknownKey = errors.New("unknown flag")
+	// ErrShadowed 参数已经被更高优先级的来源设置
+	ErrShadowed = errors.New("shadowed by higher priority source")
 )
 
+// resetter 由支持多次Set追加值的参数实现，Reset会丢弃已有的值，
+// 通过配置来源设置参数时，总是覆盖而不是追加
+type resetter interface {
+	Reset(value string) error
+}
+
 // NamedFlagSets 存储了命名参数集合
 type NamedFlagSets struct {
 	// order 为命名参数的名称排序
@@ -16,16 +35,33 @@ type NamedFlagSets struct {
 
 	// keySource 存储所有参数的来源
 	keySource map[string]Source
+	// rawValues 存储所有参数最后一次被设置的原始值
+	rawValues map[string]string
+	// shadowed 存储所有参数被更高优先级来源覆盖的值
+	shadowed map[string][]SourceValue
 
 	fs *flag.FlagSet
 
 	validateTags map[string]string
+	// secretKeys 存储所有通过secret标签标记的敏感参数
+	secretKeys map[string]bool
+	// defValues 存储所有参数未脱敏的默认值
+	defValues map[string]string
+	// aliases 存储所有通过alias标签声明的别名，key为别名完整名称，value为参数完整名称
+	aliases map[string]string
+	// secretResolver 解析敏感参数的引用，被覆盖的敏感参数恢复生效时使用
+	secretResolver func(value string) (string, error)
 }
 
 func NewNamedFlagSets() *NamedFlagSets {
 	return &NamedFlagSets{
 		keySource:    map[string]Source{},
+		rawValues:    map[string]string{},
+		shadowed:     map[string][]SourceValue{},
 		validateTags: map[string]string{},
+		secretKeys:   map[string]bool{},
+		defValues:    map[string]string{},
+		aliases:      map[string]string{},
 	}
 }
 
@@ -56,7 +92,8 @@ func (nfs *NamedFlagSets) VisitAll(fn func(p string, f *flag.Flag)) {
 	}
 }
 
-func envKey(prefix string, name string) (key string) {
+// EnvKey 返回完整名称为name的参数对应的环境变量名称，如 GF_REDIS_ADDR
+func EnvKey(prefix string, name string) (key string) {
 	if prefix == "" {
 		return strings.ReplaceAll(strings.ToUpper(name), "-", "_")
 	}
@@ -69,7 +106,7 @@ func (nfs *NamedFlagSets) CanSet(key string, source Source) bool {
 	if nfs.keySource[key] == nil {
 		return true
 	}
-	return source.order() <= nfs.keySource[key].order()
+	return compareSource(source, nfs.keySource[key]) <= 0
 }
 
 type envFlag struct {
@@ -87,18 +124,44 @@ func (nfs *NamedFlagSets) BindFlagSet(fs *flag.FlagSet, envPrefix string) {
 		if prefix != "" {
 			name = prefix + "-" + f.Name
 		}
-		key := envKey(envPrefix, name)
+		key := EnvKey(envPrefix, name)
 		envFlags = append(envFlags, envFlag{
 			envKey:  key,
 			flagKey: name,
 		})
+		usage := f.Usage
 		if validate, ok := nfs.validateTags[name]; ok {
-			fs.Var(f.Value, name, fmt.Sprintf("%s [%s] (env %s)", f.Usage, validate, key))
-		} else {
-			fs.Var(f.Value, name, fmt.Sprintf("%s (env %s)", f.Usage, key))
+			usage = fmt.Sprintf("%s [%s]", usage, validate)
+		}
+		if isSecretValue(f.Value) || nfs.secretKeys[name] {
+			usage += " (secret)"
 		}
-		fs.Lookup(name).DefValue = f.DefValue
+		fs.Var(f.Value, name, fmt.Sprintf("%s (env %s)", usage, key))
+		nfs.defValues[name] = f.DefValue
+		// 用法说明中的默认值需要脱敏
+		fs.Lookup(name).DefValue = nfs.Redact(name, f.DefValue)
 	})
+	// 别名与参数共享同一个值，通过命令行和环境变量使用别名时会记录为参数本身的来源
+	aliases := make([]string, 0, len(nfs.aliases))
+	for alias := range nfs.aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		name := nfs.aliases[alias]
+		f := fs.Lookup(name)
+		if f == nil {
+			panic(fmt.Errorf("alias %s refers to unknown flag %s", alias, name))
+		}
+		if fs.Lookup(alias) != nil {
+			panic(fmt.Errorf("alias %s of flag %s conflicts with an existing flag", alias, name))
+		}
+		key := EnvKey(envPrefix, alias)
+		envFlags = append(envFlags, envFlag{envKey: key, flagKey: alias})
+		fs.Var(f.Value, alias, fmt.Sprintf("deprecated, use -%s instead (env %s)", name, key))
+		fs.Lookup(alias).DefValue = f.DefValue
+	}
+
 	// parse flags from os.Args
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		panic(err)
@@ -106,35 +169,290 @@ func (nfs *NamedFlagSets) BindFlagSet(fs *flag.FlagSet, envPrefix string) {
 
 	// record os.Args flags
 	fs.Visit(func(f *flag.Flag) {
-		nfs.keySource[f.Name] = sourceArgs
+		name := nfs.ResolveAlias(f.Name)
+		nfs.keySource[name] = sourceArgs
+		nfs.rawValues[name] = f.Value.String()
 	})
 	// parse flags from env
 	for i := range envFlags {
-		if !nfs.CanSet(envFlags[i].flagKey, sourceEnvrioment) {
-			continue
-		}
 		envValue, ok := os.LookupEnv(envFlags[i].envKey)
 		if !ok {
 			continue
 		}
-		if err := fs.Set(envFlags[i].flagKey, envValue); err != nil {
+		flagKey := nfs.ResolveAlias(envFlags[i].flagKey)
+		if !nfs.CanSet(flagKey, sourceEnvrioment) {
+			nfs.shadow(flagKey, SourceValue{Source: sourceEnvrioment, Value: envValue})
+			continue
+		}
+		if err := fs.Set(flagKey, envValue); err != nil {
 			panic(err)
 		}
-		nfs.keySource[envFlags[i].flagKey] = sourceEnvrioment
+		nfs.keySource[flagKey] = sourceEnvrioment
+		nfs.rawValues[flagKey] = envValue
+	}
+}
+
+// ResolveAlias 如果key为别名，记录弃用警告并返回参数的完整名称，否则原样返回
+func (nfs *NamedFlagSets) ResolveAlias(key string) string {
+	name, ok := nfs.aliases[key]
+	if !ok {
+		return key
+	}
+	slog.Warn("flag alias is deprecated", "alias", key, "flag", name)
+	return name
+}
+
+// SetAliases 设置参数别名，aliases的key为别名完整名称，value为参数完整名称
+func (nfs *NamedFlagSets) SetAliases(aliases map[string]string) {
+	if nfs.aliases == nil {
+		nfs.aliases = make(map[string]string, len(aliases))
+	}
+	for alias, name := range aliases {
+		if prev, ok := nfs.aliases[alias]; ok && prev != name {
+			panic(fmt.Errorf("alias %s is used by both flag %s and %s", alias, prev, name))
+		}
+		nfs.aliases[alias] = name
 	}
 }
 
+// Lookup 返回完整名称为key的参数，如果不存在则返回nil
+func (nfs *NamedFlagSets) Lookup(key string) *flag.Flag {
+	if nfs.fs == nil {
+		return nil
+	}
+	return nfs.fs.Lookup(key)
+}
+
+// Set 使用source设置参数，key可以是参数的别名
 func (nfs *NamedFlagSets) Set(key string, value string, source Source) error {
+	key = nfs.ResolveAlias(key)
+	f := nfs.Lookup(key)
+	if f == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
 	if !nfs.CanSet(key, source) {
-		return fmt.Errorf("can not set %s from %s, already set from %s", key, source, nfs.keySource[key])
+		nfs.shadow(key, SourceValue{Source: source, Value: value})
+		return fmt.Errorf("can not set %s from %s, already set from %s: %w", key, source, nfs.keySource[key], ErrShadowed)
 	}
-	if err := nfs.fs.Set(key, value); err != nil {
+	if err := SetValue(f, value); err != nil {
 		return err
 	}
+	if prev := nfs.keySource[key]; prev != nil && prev != source {
+		nfs.shadow(key, SourceValue{Source: prev, Value: nfs.rawValues[key]})
+	}
+	nfs.unshadow(key, source)
 	nfs.keySource[key] = source
+	nfs.rawValues[key] = value
+	return nil
+}
+
+// Resolve 使用解析后的value替换参数当前的值，参数的来源保持不变，
+// value会代替原始值成为回滚时恢复的值，用于在绑定参数之后解析敏感参数的引用
+func (nfs *NamedFlagSets) Resolve(key string, value string) error {
+	f := nfs.Lookup(key)
+	if f == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+	if err := SetValue(f, value); err != nil {
+		return err
+	}
+	if _, ok := nfs.rawValues[key]; ok {
+		nfs.rawValues[key] = value
+	} else {
+		nfs.defValues[key] = value
+	}
 	return nil
 }
 
+// Unset 删除source设置的值，key可以是参数的别名
+// 如果参数的当前值由source设置，则恢复为被覆盖的值中优先级最高的一个，没有被覆盖的值时恢复为默认值，
+// 否则只删除source被覆盖的值
+func (nfs *NamedFlagSets) Unset(key string, source Source) error {
+	key = nfs.ResolveAlias(key)
+	f := nfs.Lookup(key)
+	if f == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+	if nfs.keySource[key] != source {
+		nfs.unshadow(key, source)
+		return nil
+	}
+	if len(nfs.shadowed[key]) == 0 {
+		if err := SetValue(f, nfs.defValues[key]); err != nil {
+			return err
+		}
+		delete(nfs.keySource, key)
+		delete(nfs.rawValues, key)
+		return nil
+	}
+	// 被覆盖的敏感参数保存的是未解析的引用，恢复生效时才解析
+	sv := nfs.shadowed[key][0]
+	value := sv.Value
+	if nfs.secretResolver != nil && nfs.IsSecret(key) {
+		resolved, err := nfs.secretResolver(value)
+		if err != nil {
+			return fmt.Errorf("resolve %s from %s failed: %w", key, sv.Source, err)
+		}
+		value = resolved
+	}
+	if err := SetValue(f, value); err != nil {
+		return err
+	}
+	nfs.unshadow(key, sv.Source)
+	nfs.keySource[key] = sv.Source
+	nfs.rawValues[key] = value
+	return nil
+}
+
+// SourceValue 记录了来源设置的值
+type SourceValue struct {
+	Source Source
+	Value  string
+}
+
+// shadow 记录key被更高优先级来源覆盖的值，每个来源只保留最后一次的值
+func (nfs *NamedFlagSets) shadow(key string, sv SourceValue) {
+	nfs.unshadow(key, sv.Source)
+	nfs.shadowed[key] = append(nfs.shadowed[key], sv)
+	sort.SliceStable(nfs.shadowed[key], func(i, j int) bool {
+		return compareSource(nfs.shadowed[key][i].Source, nfs.shadowed[key][j].Source) < 0
+	})
+}
+
+func (nfs *NamedFlagSets) unshadow(key string, source Source) {
+	values := nfs.shadowed[key][:0]
+	for _, sv := range nfs.shadowed[key] {
+		if sv.Source != source {
+			values = append(values, sv)
+		}
+	}
+	nfs.shadowed[key] = values
+}
+
+// Provenance 描述了参数的当前值及其来源
+type Provenance struct {
+	Key     string
+	Value   string
+	Default string
+	// Source 为当前值的来源，为nil时表示使用的是默认值
+	Source Source
+	// Shadowed 为被更高优先级来源覆盖的值，按优先级从高到低排序
+	Shadowed []SourceValue
+}
+
+// Provenance 返回完整名称为key的参数的当前值及其来源
+func (nfs *NamedFlagSets) Provenance(key string) (Provenance, bool) {
+	f := nfs.Lookup(key)
+	if f == nil {
+		return Provenance{}, false
+	}
+	return Provenance{
+		Key:      key,
+		Value:    f.Value.String(),
+		Default:  nfs.defValues[key],
+		Source:   nfs.keySource[key],
+		Shadowed: append([]SourceValue(nil), nfs.shadowed[key]...),
+	}, true
+}
+
+// SetValue 设置参数的值，对于支持追加的参数，会覆盖已有的值
+func SetValue(f *flag.Flag, value string) error {
+	if r, ok := f.Value.(resetter); ok {
+		return r.Reset(value)
+	}
+	return f.Value.Set(value)
+}
+
+// Tx 记录一批参数变更，用于在变更失败时回滚
+type Tx struct {
+	nfs     *NamedFlagSets
+	changes []txChange
+}
+
+type txChange struct {
+	key        string
+	raw        string
+	hasRaw     bool
+	prevSource Source
+	shadowed   []SourceValue
+	// shadowedOnly 表示值被更高优先级来源覆盖，没有被修改
+	shadowedOnly bool
+}
+
+// Begin 开始一批参数变更
+func (nfs *NamedFlagSets) Begin() *Tx {
+	return &Tx{nfs: nfs}
+}
+
+// Set 设置参数，并记录参数变更前的值
+func (tx *Tx) Set(key string, value string, source Source) error {
+	key = tx.nfs.ResolveAlias(key)
+	change := txChange{
+		key:        key,
+		prevSource: tx.nfs.keySource[key],
+		shadowed:   append([]SourceValue(nil), tx.nfs.shadowed[key]...),
+	}
+	change.raw, change.hasRaw = tx.nfs.rawValues[key]
+	err := tx.nfs.Set(key, value, source)
+	if errors.Is(err, ErrUnknownKey) {
+		return err
+	}
+	// 设置失败时，参数的值也可能已经被修改，同样需要记录
+	change.shadowedOnly = errors.Is(err, ErrShadowed)
+	tx.changes = append(tx.changes, change)
+	return err
+}
+
+// Unset 删除source设置的值，并记录参数变更前的值
+func (tx *Tx) Unset(key string, source Source) error {
+	key = tx.nfs.ResolveAlias(key)
+	change := txChange{
+		key:          key,
+		prevSource:   tx.nfs.keySource[key],
+		shadowed:     append([]SourceValue(nil), tx.nfs.shadowed[key]...),
+		shadowedOnly: tx.nfs.keySource[key] != source,
+	}
+	change.raw, change.hasRaw = tx.nfs.rawValues[key]
+	err := tx.nfs.Unset(key, source)
+	if errors.Is(err, ErrUnknownKey) {
+		return err
+	}
+	tx.changes = append(tx.changes, change)
+	return err
+}
+
+// Rollback 按相反的顺序恢复所有参数变更前的值及来源
+func (tx *Tx) Rollback() error {
+	var errs error
+	for i := len(tx.changes) - 1; i >= 0; i-- {
+		c := tx.changes[i]
+		tx.nfs.shadowed[c.key] = c.shadowed
+		if c.shadowedOnly {
+			continue
+		}
+		f := tx.nfs.Lookup(c.key)
+		raw := c.raw
+		if !c.hasRaw {
+			raw = tx.nfs.defValues[c.key]
+		}
+		if err := SetValue(f, raw); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("rollback %s failed: %w", c.key, err))
+		}
+		if c.hasRaw {
+			tx.nfs.rawValues[c.key] = c.raw
+		} else {
+			delete(tx.nfs.rawValues, c.key)
+		}
+		if c.prevSource != nil {
+			tx.nfs.keySource[c.key] = c.prevSource
+		} else {
+			delete(tx.nfs.keySource, c.key)
+		}
+	}
+	tx.changes = nil
+	return errs
+}
+
 func (nfs *NamedFlagSets) SetValidateTags(tags map[string]string) {
 	if nfs.validateTags == nil {
 		nfs.validateTags = make(map[string]string, len(tags))
@@ -143,3 +461,48 @@ func (nfs *NamedFlagSets) SetValidateTags(tags map[string]string) {
 		nfs.validateTags[name] = tags[name]
 	}
 }
+
+// ValidateTag 返回完整名称为key的参数的validate标签
+func (nfs *NamedFlagSets) ValidateTag(key string) string {
+	return nfs.validateTags[key]
+}
+
+// SetSecretKeys 标记敏感参数，tags的key为参数完整名称，value为secret标签的值
+func (nfs *NamedFlagSets) SetSecretKeys(tags map[string]string) {
+	if nfs.secretKeys == nil {
+		nfs.secretKeys = make(map[string]bool, len(tags))
+	}
+	for name := range tags {
+		if secret, _ := strconv.ParseBool(tags[name]); secret {
+			nfs.secretKeys[name] = true
+		}
+	}
+}
+
+// SetSecretResolver 设置敏感参数引用的解析函数，被更高优先级来源覆盖的敏感参数不会被解析，
+// Unset使其恢复生效时使用fn解析，fn需要原样返回不是引用的值
+func (nfs *NamedFlagSets) SetSecretResolver(fn func(value string) (string, error)) {
+	nfs.secretResolver = fn
+}
+
+func isSecretValue(v flag.Value) bool {
+	s, ok := v.(interface{ IsSecret() bool })
+	return ok && s.IsSecret()
+}
+
+// IsSecret 判断完整名称为key的参数是否为敏感参数
+func (nfs *NamedFlagSets) IsSecret(key string) bool {
+	if nfs.secretKeys[key] {
+		return true
+	}
+	f := nfs.Lookup(key)
+	return f != nil && isSecretValue(f.Value)
+}
+
+// Redact 如果key为敏感参数并且value不为空，则返回脱敏后的值
+func (nfs *NamedFlagSets) Redact(key string, value string) string {
+	if value == "" || !nfs.IsSecret(key) {
+		return value
+	}
+	return flagvar.Redacted
+}
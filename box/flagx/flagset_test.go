@@ -0,0 +1,210 @@
+package flagx
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/daemtri/di/box/flagvar"
+)
+
+var (
+	txTestHigh = NewSource("tx-test-high")
+	txTestLow  = NewSource("tx-test-low")
+)
+
+type txTestFlags struct {
+	nfs    *NamedFlagSets
+	addr   *string
+	tags   []string
+	labels map[string]string
+}
+
+// newTxTestFlags 创建db-addr、db-tags、db-labels三个参数，并使用args解析
+func newTxTestFlags(t *testing.T, args ...string) *txTestFlags {
+	t.Helper()
+	tf := &txTestFlags{nfs: NewNamedFlagSets(), labels: map[string]string{"k": "v"}}
+	fs := tf.nfs.FlagSet("db")
+	tf.addr = fs.String("addr", "localhost", "address")
+	fs.Var(flagvar.Slice(&tf.tags, "a", "b"), "tags", "tags")
+	fs.Var(flagvar.StringMap(&tf.labels), "labels", "labels")
+
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+	}()
+	os.Args = append([]string{oldArgs[0]}, args...)
+	tf.nfs.BindFlagSet(flag.NewFlagSet(oldArgs[0], flag.ContinueOnError), "TX_TEST")
+	return tf
+}
+
+func (tf *txTestFlags) assertDefaults(t *testing.T) {
+	t.Helper()
+	if *tf.addr != "localhost" {
+		t.Errorf("db-addr = %s, want localhost", *tf.addr)
+	}
+	if !reflect.DeepEqual(tf.tags, []string{"a", "b"}) {
+		t.Errorf("db-tags = %v, want [a b]", tf.tags)
+	}
+	if !reflect.DeepEqual(tf.labels, map[string]string{"k": "v"}) {
+		t.Errorf("db-labels = %v, want map[k:v]", tf.labels)
+	}
+	for _, key := range []string{"db-addr", "db-tags", "db-labels"} {
+		pv, _ := tf.nfs.Provenance(key)
+		if pv.Source != nil || len(pv.Shadowed) > 0 {
+			t.Errorf("%s provenance not restored: %+v", key, pv)
+		}
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	tf := newTxTestFlags(t)
+	tx := tf.nfs.Begin()
+	layer1 := NewLayerSource(txTestLow, "base.yaml", 1)
+	layer2 := NewLayerSource(txTestLow, "prod.yaml", 2)
+	for _, item := range []struct {
+		key, value string
+		source     Source
+	}{
+		{"db-addr", "layer1", layer1},
+		{"db-tags", "[c,d]", layer1},
+		{"db-labels", "x=y", layer1},
+		{"db-addr", "layer2", layer2},
+		{"db-tags", "e", layer2},
+	} {
+		if err := tx.Set(item.key, item.value, item.source); err != nil {
+			t.Fatalf("Set %s: %v", item.key, err)
+		}
+	}
+	if *tf.addr != "layer2" || !reflect.DeepEqual(tf.tags, []string{"e"}) || tf.labels["x"] != "y" {
+		t.Fatalf("values not set: addr=%s, tags=%v, labels=%v", *tf.addr, tf.tags, tf.labels)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	tf.assertDefaults(t)
+}
+
+func TestTxRollbackToPreviousSource(t *testing.T) {
+	tf := newTxTestFlags(t)
+	if err := tf.nfs.Set("db-addr", "low", txTestLow); err != nil {
+		t.Fatal(err)
+	}
+	tx := tf.nfs.Begin()
+	if err := tx.Set("db-addr", "high", txTestHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	pv, _ := tf.nfs.Provenance("db-addr")
+	if pv.Value != "low" || pv.Source != txTestLow || len(pv.Shadowed) > 0 {
+		t.Errorf("unexpected provenance after rollback: %+v", pv)
+	}
+}
+
+func TestTxShadowed(t *testing.T) {
+	tf := newTxTestFlags(t, "--db-addr=cli")
+	tx := tf.nfs.Begin()
+	if err := tx.Set("db-addr", "file", txTestLow); !errors.Is(err, ErrShadowed) {
+		t.Fatalf("Set error = %v, want ErrShadowed", err)
+	}
+	pv, _ := tf.nfs.Provenance("db-addr")
+	if pv.Value != "cli" || pv.Source != sourceArgs || len(pv.Shadowed) != 1 || pv.Shadowed[0].Value != "file" {
+		t.Fatalf("unexpected provenance: %+v", pv)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	pv, _ = tf.nfs.Provenance("db-addr")
+	if pv.Value != "cli" || pv.Source != sourceArgs || len(pv.Shadowed) > 0 {
+		t.Errorf("shadowed key changed by rollback: %+v", pv)
+	}
+}
+
+func TestTxUnknownKey(t *testing.T) {
+	tf := newTxTestFlags(t)
+	tx := tf.nfs.Begin()
+	if err := tx.Set("db-missing", "x", txTestLow); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Set error = %v, want ErrUnknownKey", err)
+	}
+	if len(tx.changes) != 0 {
+		t.Errorf("unknown key recorded: %+v", tx.changes)
+	}
+}
+
+func TestUnset(t *testing.T) {
+	tests := []struct {
+		name       string
+		set        []Source
+		unset      Source
+		want       string
+		wantSource Source
+	}{
+		{name: "fallback to default", set: []Source{txTestLow}, unset: txTestLow, want: "localhost"},
+		{name: "fallback to shadowed", set: []Source{txTestLow, txTestHigh}, unset: txTestHigh, want: "tx-test-low", wantSource: txTestLow},
+		{name: "remove shadowed", set: []Source{txTestHigh, txTestLow}, unset: txTestLow, want: "tx-test-high", wantSource: txTestHigh},
+		{name: "not set", unset: txTestLow, want: "localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf := newTxTestFlags(t)
+			for _, source := range tt.set {
+				if err := tf.nfs.Set("db-addr", source.String()[3:], source); err != nil && !errors.Is(err, ErrShadowed) {
+					t.Fatal(err)
+				}
+			}
+			if err := tf.nfs.Unset("db-addr", tt.unset); err != nil {
+				t.Fatal(err)
+			}
+			pv, _ := tf.nfs.Provenance("db-addr")
+			if pv.Value != tt.want || pv.Source != tt.wantSource || len(pv.Shadowed) > 0 {
+				t.Errorf("unexpected provenance after Unset: %+v", pv)
+			}
+		})
+	}
+}
+
+func TestTxUnsetRollback(t *testing.T) {
+	tf := newTxTestFlags(t)
+	if err := tf.nfs.Set("db-tags", "c", txTestLow); err != nil {
+		t.Fatal(err)
+	}
+	tx := tf.nfs.Begin()
+	if err := tx.Unset("db-tags", txTestLow); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tf.tags, []string{"a", "b"}) {
+		t.Fatalf("db-tags = %v, want default [a b]", tf.tags)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	pv, _ := tf.nfs.Provenance("db-tags")
+	if !reflect.DeepEqual(tf.tags, []string{"c"}) || pv.Source != txTestLow {
+		t.Errorf("Unset not rolled back: tags=%v, provenance=%+v", tf.tags, pv)
+	}
+}
+
+func TestUnsetResolvesShadowedSecret(t *testing.T) {
+	tf := newTxTestFlags(t)
+	tf.nfs.SetSecretKeys(map[string]string{"db-addr": "true"})
+	tf.nfs.SetSecretResolver(func(value string) (string, error) {
+		return strings.TrimPrefix(value, "ref://"), nil
+	})
+	if err := tf.nfs.Set("db-addr", "high", txTestHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.nfs.Set("db-addr", "ref://resolved", txTestLow); !errors.Is(err, ErrShadowed) {
+		t.Fatalf("Set() = %v, want ErrShadowed", err)
+	}
+	if err := tf.nfs.Unset("db-addr", txTestHigh); err != nil {
+		t.Fatal(err)
+	}
+	if f := tf.nfs.Lookup("db-addr"); f.Value.String() != "resolved" {
+		t.Errorf("db-addr = %s, want resolved", f.Value)
+	}
+}
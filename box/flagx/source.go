@@ -13,20 +13,25 @@ var (
 )
 
 type Source interface {
-	order() int
+	order() (index int, layer int)
 	String() string
 }
 
 type source struct {
 	index int
+	// layer 为同一来源中的层级，层级越高优先级越高
+	layer int
 	name  string
 }
 
-func (s source) order() int {
-	return s.index
+func (s source) order() (int, int) {
+	return s.index, s.layer
 }
 
 func (s source) String() string {
+	if s.layer > 0 {
+		return fmt.Sprintf("[%d.%d]%s", s.index, s.layer, s.name)
+	}
 	return fmt.Sprintf("[%d]%s", s.index, s.name)
 }
 
@@ -41,3 +46,27 @@ func NewSource(name string) Source {
 	sourceNames = append(sourceNames, name)
 	return source{index: len(sourceNames) - 1, name: name}
 }
+
+// NewLayerSource 创建parent中的一层来源，layer从1开始，layer越大优先级越高，
+// 所有层的优先级都高于parent本身，低于比parent优先级更高的来源
+func NewLayerSource(parent Source, name string, layer int) Source {
+	index, _ := parent.order()
+	if layer <= 0 {
+		panic(fmt.Errorf("invalid layer %d of source %s", layer, parent))
+	}
+	parentName := parent.String()
+	if p, ok := parent.(source); ok {
+		parentName = p.name
+	}
+	return source{index: index, layer: layer, name: parentName + ":" + name}
+}
+
+// compareSource 比较两个来源的优先级，返回值小于0表示a的优先级更高
+func compareSource(a, b Source) int {
+	ai, al := a.order()
+	bi, bl := b.order()
+	if ai != bi {
+		return ai - bi
+	}
+	return bl - al
+}
@@ -2,9 +2,11 @@ package box
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/joho/godotenv"
 	"golang.org/x/exp/slog"
@@ -18,6 +20,34 @@ func init() {
 	}
 }
 
+const printConfigAnnotated = "annotated"
+
+// printConfigValue --print-config参数，可以作为bool参数使用，也可以指定为annotated
+type printConfigValue struct {
+	mode string
+}
+
+func (pv *printConfigValue) String() string {
+	if pv == nil {
+		return ""
+	}
+	return pv.mode
+}
+
+func (pv *printConfigValue) Set(s string) error {
+	switch s {
+	case "true", printConfigAnnotated:
+		pv.mode = s
+	case "false", "":
+		pv.mode = ""
+	default:
+		return fmt.Errorf("invalid print-config mode %q, must be true, false or %s", s, printConfigAnnotated)
+	}
+	return nil
+}
+
+func (pv *printConfigValue) IsBoolFlag() bool { return true }
+
 // InitFunc 初始化函数
 type InitFunc func(context.Context) error
 
@@ -27,30 +57,52 @@ type namedInitFunc struct {
 }
 
 type initializer[T any] struct {
-	beforeFuncs []namedInitFunc
-	instance    T
+	beforeFuncs  []namedInitFunc
+	strictConfig bool
+	instance     T
 }
 
 func (it *initializer[T]) Build(ctx context.Context) (*initializer[T], error) {
-	// register config loader
-	configLoaders := Invoke[All[*configLoaderBuilder]](ctx)
-
 	// parser args and envronment
-	printConfig := nfs.FlagSet().Bool("print-config", false, "print configuration information")
+	printConfig := &printConfigValue{}
+	nfs.FlagSet().Var(printConfig, "print-config", "print configuration information and exit, use --print-config=annotated to print the source of each value")
+	generateConfig := nfs.FlagSet().Bool("generate-config", false, "print a commented configuration template with default values and exit")
 	nfs.BindFlagSet(flag.CommandLine, envPrefix)
+	if *generateConfig {
+		if err := EncodeConfigTemplate(os.Stdout); err != nil {
+			_, _ = fmt.Fprintln(os.Stdout, "EncodeConfigTemplate error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if err := resolveSecretFlags(); err != nil {
+		return nil, fmt.Errorf("resolve secret flags failed: %w", err)
+	}
+
+	// register config loader
+	// 加载器需要在解析命令行参数及环境变量之后构建，才能使用实际的参数值校验，校验失败的加载器会被忽略
+	configLoaders := Invoke[All[*configLoaderBuilder]](ctx)
 
 	// load config from config file or other source
+	var unknown []UnknownKey
 	for i := range configLoaders {
-		if err := configLoaders[i].Load(ctx, func(items []ConfigItem) {
-			SetConfig(items, configLoaders[i].source)
-		}); err != nil {
+		loaderUnknown, err := loadConfig(ctx, configLoaders[i])
+		if err != nil {
 			return nil, fmt.Errorf("load configuration %s failed: %w", configLoaders[i].source, err)
 		}
+		unknown = append(unknown, loaderUnknown...)
+	}
+	if err := checkUnknownKeys(it.strictConfig, unknown); err != nil {
+		return nil, err
 	}
 
 	// print config
-	if *printConfig {
-		err := EncodeFlags(os.Stdout)
+	if printConfig.mode != "" {
+		encode := EncodeFlags
+		if printConfig.mode == printConfigAnnotated {
+			encode = EncodeAnnotatedFlags
+		}
+		err := encode(os.Stdout)
 		if err != nil {
 			_, _ = fmt.Fprintln(os.Stdout, "EncodeFlags error", err)
 			os.Exit(1)
@@ -67,3 +119,68 @@ func (it *initializer[T]) Build(ctx context.Context) (*initializer[T], error) {
 	it.instance = Invoke[T](ctx)
 	return it, nil
 }
+
+// loadConfig 使用加载器加载配置，Load返回之前被拒绝的配置会导致加载失败，
+// 之后（如监听到配置变更时）被拒绝的配置只会记录日志，保持原有配置不变。
+// Load返回之前出现的未知key会被返回，由调用者决定是否失败，之后出现的只记录日志
+func loadConfig(ctx context.Context, loader *configLoaderBuilder) ([]UnknownKey, error) {
+	var (
+		mux      sync.Mutex
+		loaded   bool
+		rejected error
+		unknown  []UnknownKey
+	)
+	apply := func(batches []configBatch) {
+		batchUnknown, err := applyConfig(batches, loader.source)
+		mux.Lock()
+		defer mux.Unlock()
+		if loaded {
+			warnUnknownKeys(batchUnknown)
+		} else {
+			unknown = append(unknown, batchUnknown...)
+		}
+		if err == nil {
+			return
+		}
+		var rejectedErr *ConfigRejectedError
+		if !errors.As(err, &rejectedErr) {
+			slog.Warn("set config failed", "source", loader.source, "error", err)
+			return
+		}
+		if loaded {
+			slog.Error("config rejected", "source", loader.source, "error", err)
+			return
+		}
+		rejected = errors.Join(rejected, err)
+	}
+	var err error
+	if layered, ok := loader.ConfigLoader.(LayeredConfigLoader); ok {
+		err = layered.LoadLayers(ctx, func(layers []ConfigLayer) {
+			mapped := make([]ConfigLayer, len(layers))
+			for i := range layers {
+				mapped[i] = ConfigLayer{
+					Name:    layers[i].Name,
+					Items:   loader.mapKeys(layers[i].Items),
+					Removed: loader.mapRemoved(layers[i].Removed),
+				}
+			}
+			apply(layerBatches(mapped, loader.source))
+		})
+	} else if updating, ok := loader.ConfigLoader.(UpdatingConfigLoader); ok {
+		err = updating.LoadUpdates(ctx, func(update ConfigUpdate) {
+			apply([]configBatch{{
+				items:   loader.mapKeys(update.Items),
+				removed: loader.mapRemoved(update.Removed),
+				source:  loader.source,
+			}})
+		})
+	} else {
+		err = loader.Load(ctx, func(items []ConfigItem) {
+			apply([]configBatch{{items: loader.mapKeys(items), source: loader.source}})
+		})
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	loaded = true
+	return unknown, errors.Join(err, rejected)
+}
@@ -7,6 +7,10 @@ import (
 type options struct {
 	opts       []di.Option
 	flagPrefix string
+	// hasFlags 是否使用WithFlags绑定了参数
+	hasFlags bool
+	name     string
+	reload   bool
 }
 
 func newOptions() *options {
@@ -26,6 +30,7 @@ func (of optionsFunc) apply(o *options) { of(o) }
 func WithName(name string) Option {
 	return optionsFunc(func(o *options) {
 		o.opts = append(o.opts, di.WithName(name))
+		o.name = name
 	})
 }
 
@@ -33,6 +38,7 @@ func WithFlags(prefix string) Option {
 	return optionsFunc(func(o *options) {
 		o.opts = append(o.opts, di.WithFlagset(nfs.FlagSet(prefix)))
 		o.flagPrefix = prefix
+		o.hasFlags = true
 	})
 }
 
@@ -54,3 +60,12 @@ func WithOverride() Option {
 		o.opts = append(o.opts, di.WithOverride())
 	})
 }
+
+// WithReload 当对象绑定的参数发生变更时，使用新的参数重新构建对象，
+// 并通过 *di.Live[T] 原子替换，旧对象如果实现了io.Closer，会在等待 SetReloadDrain 设置的时间后关闭
+// 使用方需要依赖 *di.Live[T] 而不是 T，才能获取到重新构建后的对象
+func WithReload() Option {
+	return optionsFunc(func(o *options) {
+		o.reload = true
+	})
+}
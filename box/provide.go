@@ -9,7 +9,7 @@ import (
 	"github.com/daemtri/di/box/validate"
 )
 
-func provide[T any](b Builder[T], opts ...Option) {
+func resolveProvideOptions(b any, opts ...Option) *options {
 	if nfsIsParsed {
 		panic(fmt.Errorf("不能在Build之后再执行Provide: %T", b))
 	}
@@ -18,7 +18,23 @@ func provide[T any](b Builder[T], opts ...Option) {
 		opts[i].apply(opt)
 	}
 	nfs.SetValidateTags(validate.ParseValidateString(opt.flagPrefix, b))
+	nfs.SetSecretKeys(validate.ParseTagString(opt.flagPrefix, b, "secret"))
+	nfs.SetAliases(validate.ParseAliases(opt.flagPrefix, b))
+	if opt.hasFlags {
+		registerOptionPrefix(b, opt.flagPrefix)
+	}
+	return opt
+}
+
+func provide[T any](b Builder[T], opts ...Option) {
+	opt := resolveProvideOptions(b, opts...)
+	if _, ok := b.(*structBuilder[T]); ok && opt.reload {
+		panic(fmt.Errorf("ProvideStruct不支持WithReload，结构体每次构建返回的都是同一个对象: %s", reflectType[T]()))
+	}
 	di.Provide[T](b, opt.opts...)
+	if opt.reload {
+		provideLive[T](opt.name)
+	}
 }
 
 // Provide 实现智能提供数据和注入数据的功能
@@ -41,3 +57,52 @@ func Provide[T any](fn any, opts ...Option) {
 		provide(newInstanceBuilder(instance), opts...)
 	}
 }
+
+// Instance 泛型构造函数的一个实例化版本，使用For创建
+type Instance interface {
+	provide(opts ...Option)
+}
+
+type instance[T any] struct {
+	fn   any
+	opts []Option
+}
+
+func (i instance[T]) provide(opts ...Option) {
+	provide(newDynamicParamsFunctionBuilder[T](i.fn, nil), append(opts, i.opts...)...)
+}
+
+// For 声明实例化后的构造函数fn提供的类型为T，供ProvideFor使用
+// fn必须返回 (T,error) 或者 (X, error),X 实现了T接口，参数与Provide的函数参数规则一致，
+// opts只作用于当前实例，在ProvideFor的opts之后应用，如各实例的参数结构体相同时，需要分别指定WithFlags
+func For[T any](fn any, opts ...Option) Instance {
+	return instance[T]{fn: fn, opts: opts}
+}
+
+// ProvideFor 批量提供同一个泛型构造函数的多个实例化版本，opts作用于所有实例
+// Go的反射无法实例化泛型函数，所以需要使用For显式列出每一个实例化后的函数及其提供的类型
+//
+//	box.ProvideFor([]box.Instance{
+//		box.For[*repository.Repo[User]](repository.NewRepo[User]),
+//		box.For[*repository.Repo[Order]](repository.NewRepo[Order]),
+//	}, box.WithSelect[*sql.DB]("replica"))
+func ProvideFor(instances []Instance, opts ...Option) {
+	for i := range instances {
+		instances[i].provide(opts...)
+	}
+}
+
+// ProvideStruct 自动装配结构体并提供，T必须是结构体指针
+// 结构体中 inject 标签的字段会被注入依赖，flag 标签的字段会被绑定到参数并使用 validate 标签校验，
+// 如果结构体实现了 Init(ctx context.Context) error 方法，则会在注入完成后调用
+// 结构体本身即是构建结果，不能重新构建出新的对象，所以不支持WithReload，需要响应参数变更时实现Retrofiter
+//
+//	type UserService struct {
+//		Addr string               `flag:"addr" default:":8080" validate:"required"`
+//		Repo contract.UserRepository `inject:"must"`
+//	}
+//
+//	box.ProvideStruct[*UserService](box.WithFlags("user"))
+func ProvideStruct[T any](opts ...Option) {
+	provide(newStructBuilder[T](), opts...)
+}
@@ -0,0 +1,95 @@
+package box
+
+import (
+	"context"
+	"testing"
+
+	"github.com/daemtri/di"
+)
+
+type provideForDB struct {
+	name string
+}
+
+type provideForUser struct{}
+
+type provideForOrder struct{}
+
+type provideForOption struct {
+	Table string `flag:"table" default:"items"`
+}
+
+type provideForRepo[T any] struct {
+	db    *provideForDB
+	table string
+}
+
+func newProvideForRepo[T any](db *provideForDB, opt *provideForOption) (*provideForRepo[T], error) {
+	return &provideForRepo[T]{db: db, table: opt.Table}, nil
+}
+
+type provideForCache[T any] struct{}
+
+func newProvideForCache[T any]() (*provideForCache[T], error) {
+	return &provideForCache[T]{}, nil
+}
+
+func TestProvideFor(t *testing.T) {
+	resetFlags(t)
+	Provide[*provideForDB](&provideForDB{name: "primary"})
+	Provide[*provideForDB](&provideForDB{name: "replica"}, WithName("replica"))
+	ProvideFor([]Instance{
+		For[*provideForRepo[provideForUser]](newProvideForRepo[provideForUser], WithFlags("user")),
+		For[*provideForRepo[provideForOrder]](newProvideForRepo[provideForOrder], WithFlags("order")),
+	}, WithSelect[*provideForDB]("replica"))
+	bindFlags(t, "--order-table=orders")
+
+	ctx := context.Background()
+	users, err := di.Build[*provideForRepo[provideForUser]](ctx)
+	if err != nil {
+		t.Fatalf("build users repo: %v", err)
+	}
+	orders, err := di.Build[*provideForRepo[provideForOrder]](ctx)
+	if err != nil {
+		t.Fatalf("build orders repo: %v", err)
+	}
+	if users.db.name != "replica" || orders.db.name != "replica" {
+		t.Errorf("shared options not applied: users=%s, orders=%s", users.db.name, orders.db.name)
+	}
+	if users.table != "items" || orders.table != "orders" {
+		t.Errorf("instance options not applied: users=%s, orders=%s", users.table, orders.table)
+	}
+}
+
+func TestProvideForWithReload(t *testing.T) {
+	resetFlags(t)
+	ProvideFor([]Instance{
+		For[*provideForCache[provideForUser]](newProvideForCache[provideForUser]),
+		For[*provideForCache[provideForOrder]](newProvideForCache[provideForOrder]),
+	}, WithReload())
+
+	ctx := context.Background()
+	users, err := di.Build[*di.Live[*provideForCache[provideForUser]]](ctx)
+	if err != nil {
+		t.Fatalf("build users live: %v", err)
+	}
+	orders, err := di.Build[*di.Live[*provideForCache[provideForOrder]]](ctx)
+	if err != nil {
+		t.Fatalf("build orders live: %v", err)
+	}
+	if users.Load() == nil || orders.Load() == nil {
+		t.Errorf("live instances not set")
+	}
+}
+
+func TestForTypeMismatch(t *testing.T) {
+	resetFlags(t)
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic when the function does not return T")
+		}
+	}()
+	ProvideFor([]Instance{
+		For[*provideForCache[provideForOrder]](newProvideForCache[provideForUser]),
+	})
+}
@@ -0,0 +1,98 @@
+package box
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/daemtri/di"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	reloadDrain = 30 * time.Second
+	// reloaders 存储了所有使用WithReload提供的对象，key为对象的类型及名称
+	reloaders = map[string]func(v any) any{}
+	reloadMux sync.Mutex
+	// buildCtx 为Build时传入的context，用于重新构建对象
+	buildCtx = context.Background()
+)
+
+// SetReloadDrain 设置重新构建对象后，旧对象被关闭之前的等待时间
+func SetReloadDrain(d time.Duration) {
+	reloadDrain = d
+}
+
+func reloaderKey(typ reflect.Type, name string) string {
+	return fmt.Sprintf("%s(%s)", typ, name)
+}
+
+// provideLive 提供T对应的 *di.Live[T]，并记录替换函数
+func provideLive[T any](name string) {
+	live := &di.Live[T]{}
+	di.Provide[*di.Live[T]](di.Func(func(ctx context.Context) (*di.Live[T], error) {
+		live.Swap(Invoke[T](ctx))
+		return live, nil
+	}), di.WithName(name), di.WithSelect[T](name))
+	reloaders[reloaderKey(reflectType[T](), name)] = func(v any) any {
+		return live.Swap(v.(T))
+	}
+}
+
+// reload 重新构建参数发生变更并且使用WithReload提供的对象，返回重新构建了的对象
+func reload(diffs []di.ComponentDiff) (map[string]bool, error) {
+	reloadMux.Lock()
+	defer reloadMux.Unlock()
+	values := make(map[string]di.Value)
+	defaultRegistrar.Visit(func(v di.Value) {
+		values[reloaderKey(v.Type, v.Name)] = v
+	})
+	reloaded := make(map[string]bool)
+	var errs error
+	for _, diff := range diffs {
+		v, ok := values[diff.String()]
+		if !ok || !v.Built() || len(diff.Flags) == 0 {
+			continue
+		}
+		swap, ok := reloaders[diff.String()]
+		if !ok {
+			continue
+		}
+		old, instance, err := defaultRegistrar.Rebuild(buildCtx, v.Type, v.Name)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("reload %s failed: %w", diff, err))
+			continue
+		}
+		reloaded[diff.String()] = true
+		// Build返回了同一个对象（如返回builder本身）时，对象仍然在使用中，不能替换和关闭
+		if sameInstance(old, instance) {
+			continue
+		}
+		closeAfterDrain(diff.String(), swap(instance))
+	}
+	return reloaded, errs
+}
+
+// sameInstance 判断重新构建的对象与旧对象是否为同一个，不可比较的类型总是认为不是同一个
+func sameInstance(old, instance any) bool {
+	if reflect.TypeOf(old) != reflect.TypeOf(instance) || !reflect.TypeOf(old).Comparable() {
+		return false
+	}
+	return old == instance
+}
+
+func closeAfterDrain(name string, old any) {
+	closer, ok := old.(io.Closer)
+	if !ok {
+		return
+	}
+	time.AfterFunc(reloadDrain, func() {
+		if err := closer.Close(); err != nil {
+			slog.Warn("close reloaded instance failed", "component", name, "error", err)
+		}
+	})
+}
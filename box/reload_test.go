@@ -0,0 +1,118 @@
+package box
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daemtri/di"
+)
+
+type reloadTestClient struct {
+	addr   string
+	closed atomic.Bool
+}
+
+func (c *reloadTestClient) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+type reloadTestOption struct {
+	Addr string `flag:"addr" default:"a"`
+}
+
+func newReloadTestClient(opt *reloadTestOption) (*reloadTestClient, error) {
+	return &reloadTestClient{addr: opt.Addr}, nil
+}
+
+// reloadTestSelf Build返回builder本身
+type reloadTestSelf struct {
+	Addr   string `flag:"addr" default:"a"`
+	closed atomic.Bool
+}
+
+func (s *reloadTestSelf) Build(ctx context.Context) (*reloadTestSelf, error) {
+	return s, nil
+}
+
+func (s *reloadTestSelf) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func setReloadDrain(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := reloadDrain
+	SetReloadDrain(d)
+	t.Cleanup(func() {
+		SetReloadDrain(old)
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestReload(t *testing.T) {
+	resetFlags(t)
+	setReloadDrain(t, 50*time.Millisecond)
+	Provide[*reloadTestClient](newReloadTestClient, WithFlags("reload-client"), WithReload())
+	bindFlags(t)
+
+	live, err := di.Build[*di.Live[*reloadTestClient]](context.Background())
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	old := live.Load()
+	if err := SetConfig([]ConfigItem{{Key: "reload-client-addr", Value: "b"}}, testSource(t)); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	current := live.Load()
+	if current == old || current.addr != "b" {
+		t.Fatalf("instance not swapped: addr=%s", current.addr)
+	}
+	// 旧对象在替换后仍可能被使用，需要等待drain之后才关闭
+	if old.closed.Load() {
+		t.Errorf("old instance closed before drain")
+	}
+	if !waitFor(t, old.closed.Load) {
+		t.Errorf("old instance not closed after drain")
+	}
+	if current.closed.Load() {
+		t.Errorf("live instance closed")
+	}
+}
+
+func TestReloadSameInstance(t *testing.T) {
+	resetFlags(t)
+	setReloadDrain(t, 0)
+	Provide[*reloadTestSelf](&reloadTestSelf{}, WithFlags("reload-self"), WithReload())
+	bindFlags(t)
+
+	live, err := di.Build[*di.Live[*reloadTestSelf]](context.Background())
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	instance := live.Load()
+	if err := SetConfig([]ConfigItem{{Key: "reload-self-addr", Value: "b"}}, testSource(t)); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	if live.Load() != instance || instance.Addr != "b" {
+		t.Fatalf("unexpected live instance: %+v", live.Load())
+	}
+	// drain为0，被关闭的话会立即发生
+	time.Sleep(20 * time.Millisecond)
+	if instance.closed.Load() {
+		t.Errorf("live instance closed after reload")
+	}
+}
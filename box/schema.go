@@ -0,0 +1,345 @@
+package box
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flagDescription 描述一个参数，用于生成配置文件的JSON Schema以及模板
+type flagDescription struct {
+	// Prefix 为参数所在的参数集合的前缀，即WithFlags的参数
+	Prefix string
+	// Key 为参数的完整名称
+	Key string
+	// Type 为参数在配置文件中的JSON类型，数组的元素类型为ItemType
+	Type     string
+	ItemType string
+	// KeyValue 表示参数为map，配置文件中使用 key=value 格式的数组
+	KeyValue bool
+	Default  string
+	Usage    string
+	Validate string
+	Secret   bool
+	// Required 表示参数必须配置，即validate标签包含required并且没有默认值
+	Required bool
+}
+
+// hasDefault 判断参数是否有默认值，没有设置default标签时参数的默认值为类型的零值，
+// 与设置为零值无法区分，所以零值都认为没有默认值
+func (fd flagDescription) hasDefault() bool {
+	switch fd.Default {
+	case "", "0", "false", "[]", "0s":
+		return false
+	}
+	return true
+}
+
+// path 返回参数在嵌套配置文档中的路径，与EncodeFlags一致，参数名称按-拆分
+func (fd flagDescription) path() []string {
+	return strings.Split(fd.Key, "-")
+}
+
+// describeFlags 返回除加载器内置参数外的所有参数的描述，按参数集合注册的顺序排列
+func describeFlags() []flagDescription {
+	var descriptions []flagDescription
+	nfs.VisitAll(func(p string, f *flag.Flag) {
+		if isBuiltinFlag(p, f) {
+			return
+		}
+		key := f.Name
+		if p != "" {
+			key = p + "-" + f.Name
+		}
+		fd := flagDescription{
+			Prefix:   p,
+			Key:      key,
+			Default:  f.DefValue,
+			Usage:    f.Usage,
+			Validate: nfs.ValidateTag(key),
+			Secret:   nfs.IsSecret(key) || isSecretFlag(f),
+		}
+		fd.Type, fd.ItemType, fd.KeyValue = jsonType(f.Value)
+		fd.Required = !fd.hasDefault() && hasRule(fd.Validate, "required")
+		descriptions = append(descriptions, fd)
+	})
+	return descriptions
+}
+
+func isSecretFlag(f *flag.Flag) bool {
+	s, ok := f.Value.(interface{ IsSecret() bool })
+	return ok && s.IsSecret()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// jsonType 返回参数值对应的JSON类型，数组返回元素的类型，map与数组一样使用csv格式设置，元素为key=value
+func jsonType(v flag.Value) (typ string, itemType string, keyValue bool) {
+	if bf, ok := v.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+		return "boolean", "", false
+	}
+	g, ok := v.(flag.Getter)
+	if !ok {
+		return "string", "", false
+	}
+	rt := reflect.TypeOf(g.Get())
+	if rt == nil {
+		return "string", "", false
+	}
+	switch rt.Kind() {
+	case reflect.Slice:
+		_, item := kindType(rt.Elem())
+		return "array", item, false
+	case reflect.Map:
+		return "array", "string", true
+	}
+	typ, _ = kindType(rt)
+	return typ, "", false
+}
+
+func kindType(rt reflect.Type) (string, string) {
+	if rt == durationType {
+		return "string", "string"
+	}
+	switch rt.Kind() {
+	case reflect.Bool:
+		return "boolean", "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number", "number"
+	}
+	return "string", "string"
+}
+
+// rule 一条validate规则，如 min=1 的Name为min，Param为1
+type rule struct {
+	Name  string
+	Param string
+}
+
+// parseRules 解析作用于参数本身的validate规则，dive之后的规则作用于元素，包含|的规则无法转换，都会被忽略
+func parseRules(tag string) []rule {
+	var rules []rule
+	for _, r := range strings.Split(tag, ",") {
+		if r == "dive" {
+			break
+		}
+		if r == "" || strings.Contains(r, "|") {
+			continue
+		}
+		name, param, _ := strings.Cut(r, "=")
+		rules = append(rules, rule{Name: name, Param: param})
+	}
+	return rules
+}
+
+func hasRule(tag string, name string) bool {
+	for _, r := range parseRules(tag) {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// typedValue 将字符串转换为JSON类型的值，转换失败时返回false
+func typedValue(typ string, value string) (any, bool) {
+	switch typ {
+	case "boolean":
+		v, err := strconv.ParseBool(value)
+		return v, err == nil
+	case "integer":
+		v, err := strconv.ParseInt(value, 10, 64)
+		return v, err == nil
+	case "number":
+		v, err := strconv.ParseFloat(value, 64)
+		return v, err == nil
+	case "string":
+		return value, true
+	}
+	return nil, false
+}
+
+// defaultValue 返回参数默认值对应的JSON值，敏感参数以及没有默认值的参数返回false
+func (fd flagDescription) defaultValue() (any, bool) {
+	if fd.Secret || !fd.hasDefault() {
+		return nil, false
+	}
+	if fd.Type != "array" {
+		return typedValue(fd.Type, fd.Default)
+	}
+	// 数组参数的默认值格式为 [a,b]
+	raw := strings.TrimSuffix(strings.TrimPrefix(fd.Default, "["), "]")
+	values, err := csv.NewReader(strings.NewReader(raw)).Read()
+	if err != nil {
+		return nil, false
+	}
+	items := make([]any, 0, len(values))
+	for _, value := range values {
+		item, ok := typedValue(fd.ItemType, value)
+		if !ok {
+			return nil, false
+		}
+		items = append(items, item)
+	}
+	return items, true
+}
+
+// schema 返回参数的JSON Schema，validate规则中的min、max、gt、gte、lt、lte、oneof、url会被转换为对应的关键字
+func (fd flagDescription) schema() map[string]any {
+	s := map[string]any{"type": fd.Type}
+	if fd.Usage != "" {
+		s["description"] = fd.Usage
+	}
+	if fd.KeyValue {
+		s["items"] = map[string]any{"type": "string", "pattern": "^[^=]+="}
+	} else if fd.Type == "array" {
+		s["items"] = map[string]any{"type": fd.ItemType}
+	}
+	if def, ok := fd.defaultValue(); ok {
+		s["default"] = def
+	}
+	if fd.Secret {
+		s["writeOnly"] = true
+	}
+	for _, r := range parseRules(fd.Validate) {
+		switch r.Name {
+		case "min", "gte":
+			fd.setBound(s, "minimum", "minLength", "minItems", r.Param)
+		case "max", "lte":
+			fd.setBound(s, "maximum", "maxLength", "maxItems", r.Param)
+		case "gt":
+			fd.setBound(s, "exclusiveMinimum", "", "", r.Param)
+		case "lt":
+			fd.setBound(s, "exclusiveMaximum", "", "", r.Param)
+		case "oneof":
+			var enum []any
+			for _, value := range strings.Fields(r.Param) {
+				if v, ok := typedValue(fd.Type, strings.Trim(value, "'")); ok {
+					enum = append(enum, v)
+				}
+			}
+			s["enum"] = enum
+		case "url", "uri":
+			s["format"] = "uri"
+		}
+	}
+	return s
+}
+
+// setBound 按参数类型设置数值、字符串长度或者数组长度的限制，没有对应关键字时忽略
+func (fd flagDescription) setBound(s map[string]any, number, length, items string, param string) {
+	var keyword string
+	switch fd.Type {
+	case "integer", "number":
+		keyword = number
+	case "string":
+		keyword = length
+	case "array":
+		keyword = items
+	}
+	if keyword == "" {
+		return
+	}
+	v, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	s[keyword] = v
+}
+
+// newFlagTree 将参数按名称中的-拆分为嵌套的节点，与EncodeFlags输出的配置文档结构一致
+func newFlagTree(descriptions []flagDescription) *schemaNode {
+	root := &schemaNode{}
+	for i := range descriptions {
+		node := root
+		path := descriptions[i].path()
+		for _, name := range path {
+			node = node.child(name)
+		}
+		// 加载器会将嵌套的key使用-连接，redis: {max-idle: 1} 与 redis: {max: {idle: 1}} 是等价的，
+		// 只有不包含-的参数在文档中的位置是唯一的，才能使用required约束
+		if len(path) == 1 && descriptions[i].Required {
+			node.required = true
+		}
+		node.flag = &descriptions[i]
+	}
+	return root
+}
+
+// sortedChildren 返回按名称排序的子节点名称
+func (n *schemaNode) sortedChildren() []string {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaNode 嵌套配置文档中的一个节点
+type schemaNode struct {
+	flag     *flagDescription
+	children map[string]*schemaNode
+	required bool
+}
+
+func (n *schemaNode) child(name string) *schemaNode {
+	if n.children == nil {
+		n.children = make(map[string]*schemaNode)
+	}
+	if n.children[name] == nil {
+		n.children[name] = &schemaNode{}
+	}
+	return n.children[name]
+}
+
+func (n *schemaNode) schema() map[string]any {
+	if len(n.children) == 0 {
+		return n.flag.schema()
+	}
+	properties := make(map[string]any, len(n.children))
+	var required []string
+	for name, child := range n.children {
+		properties[name] = child.schema()
+		if child.required {
+			required = append(required, name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	// 参数名称同时是其他参数的前缀时，该节点既可以是参数的值，也可以是包含其他参数的对象
+	if n.flag != nil {
+		return map[string]any{"anyOf": []any{n.flag.schema(), s}}
+	}
+	return s
+}
+
+// EncodeSchema 输出描述配置文件的JSON Schema，配置文件的结构与EncodeFlags一致，即参数名称按-拆分后完全嵌套，
+// 参数的类型、默认值、用法说明以及validate标签中常用的规则会被转换为对应的关键字，
+// 加载器同样接受部分嵌套的写法，所以只有名称不包含-的必须参数会出现在required中
+func EncodeSchema(w io.Writer) error {
+	root := newFlagTree(describeFlags())
+	s := root.schema()
+	if len(root.children) == 0 {
+		s = map[string]any{"type": "object"}
+	}
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,123 @@
+package box
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaTestOptions struct {
+	Host     string            `flag:"host" default:"localhost" usage:"服务地址"`
+	Port     int               `flag:"port" default:"80" validate:"min=1,max=65535"`
+	Ratio    float64           `flag:"ratio" default:"0.5" validate:"gt=0,lt=1"`
+	Debug    bool              `flag:"debug" default:"false"`
+	Timeout  time.Duration     `flag:"timeout" default:"5s"`
+	IDs      []int             `flag:"ids" default:"1,2" validate:"min=1"`
+	Labels   map[string]string `flag:"labels" default:""`
+	Mode     string            `flag:"mode" default:"fast" validate:"oneof=fast slow"`
+	Endpoint string            `flag:"endpoint" default:"" validate:"required,url"`
+	Token    string            `flag:"token" default:"default-token" secret:"true" validate:"required"`
+	Workers  int               `flag:"workers" validate:"required"`
+}
+
+func (o *schemaTestOptions) Build(ctx context.Context) (*schemaTestOptions, error) {
+	return o, nil
+}
+
+type schemaTestDB struct {
+	Addr string `flag:"addr" default:"" validate:"required"`
+	Name string `flag:"name" default:"app"`
+}
+
+func (o *schemaTestDB) Build(ctx context.Context) (*schemaTestDB, error) {
+	return o, nil
+}
+
+type schemaTestRoot struct {
+	Region string `flag:"region" validate:"required"`
+}
+
+func (o *schemaTestRoot) Build(ctx context.Context) (*schemaTestRoot, error) {
+	return o, nil
+}
+
+// schemaAt 返回schema中path对应的子schema
+func schemaAt(t *testing.T, s map[string]any, path string) map[string]any {
+	t.Helper()
+	for _, name := range strings.Split(path, ".") {
+		properties, _ := s["properties"].(map[string]any)
+		child, ok := properties[name].(map[string]any)
+		if !ok {
+			t.Fatalf("schema of %s not found in %v", path, s)
+		}
+		s = child
+	}
+	return s
+}
+
+func TestEncodeSchema(t *testing.T) {
+	resetFlags(t)
+	Provide[*schemaTestOptions](&schemaTestOptions{}, WithFlags("schema"))
+	Provide[*schemaTestDB](&schemaTestDB{}, WithFlags("schema-db"))
+	Provide[*schemaTestRoot](&schemaTestRoot{}, WithFlags(""))
+	bindFlags(t)
+
+	var buf bytes.Buffer
+	if err := EncodeSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var s map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &s); err != nil {
+		t.Fatalf("unmarshal %s: %v", buf.String(), err)
+	}
+	if s["$schema"] != "https://json-schema.org/draft/2020-12/schema" {
+		t.Errorf("$schema = %v", s["$schema"])
+	}
+	if strings.Contains(buf.String(), "default-token") {
+		t.Errorf("secret default leaked: %s", buf.String())
+	}
+
+	tests := []struct {
+		path string
+		want map[string]any
+	}{
+		{path: "schema.host", want: map[string]any{"type": "string", "default": "localhost", "description": "服务地址"}},
+		{path: "schema.port", want: map[string]any{"type": "integer", "default": 80.0, "minimum": 1.0, "maximum": 65535.0}},
+		{path: "schema.ratio", want: map[string]any{"type": "number", "default": 0.5, "exclusiveMinimum": 0.0, "exclusiveMaximum": 1.0}},
+		// 零值与没有设置默认值无法区分，不输出默认值
+		{path: "schema.debug", want: map[string]any{"type": "boolean"}},
+		{path: "schema.timeout", want: map[string]any{"type": "string", "default": "5s"}},
+		{path: "schema.ids", want: map[string]any{"type": "array", "items": map[string]any{"type": "integer"}, "default": []any{1.0, 2.0}, "minItems": 1.0}},
+		{path: "schema.labels", want: map[string]any{"type": "array", "items": map[string]any{"type": "string", "pattern": "^[^=]+="}}},
+		{path: "schema.mode", want: map[string]any{"type": "string", "default": "fast", "enum": []any{"fast", "slow"}}},
+		{path: "schema.endpoint", want: map[string]any{"type": "string", "format": "uri"}},
+		{path: "schema.token", want: map[string]any{"type": "string", "writeOnly": true}},
+		{path: "schema.workers", want: map[string]any{"type": "integer"}},
+		{path: "schema.db.addr", want: map[string]any{"type": "string"}},
+		{path: "schema.db.name", want: map[string]any{"type": "string", "default": "app"}},
+	}
+	for _, tt := range tests {
+		if got := schemaAt(t, s, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("schema of %s = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// 嵌套的参数可以使用部分嵌套的写法，只有顶层不包含-的参数是必须的
+	if got, want := s["required"], []any{"region"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("required = %v, want %v", got, want)
+	}
+	for _, path := range []string{"schema", "schema.db"} {
+		if got, ok := schemaAt(t, s, path)["required"]; ok {
+			t.Errorf("required of %s = %v, want none", path, got)
+		}
+	}
+	for _, fd := range describeFlags() {
+		if fd.Key == "schema-workers" && !fd.Required {
+			t.Errorf("int flag with validate required and no default should be required: %+v", fd)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package box
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/daemtri/di/box/flagx"
+)
+
+// SecretResolver 解析敏感参数的引用，ref为去掉 scheme:// 前缀后的部分，
+// 如 file:///run/secrets/db_password 的ref为 /run/secrets/db_password
+type SecretResolver func(ref string) (string, error)
+
+var (
+	secretResolvers = map[string]SecretResolver{
+		"file": resolveFileSecret,
+		"env":  resolveEnvSecret,
+	}
+	secretResolverMux sync.RWMutex
+)
+
+// RegisterSecretResolver 注册敏感参数引用的解析器，
+// 值为 scheme://ref 格式的敏感参数，会在设置时使用scheme对应的解析器解析成真实的值
+func RegisterSecretResolver(scheme string, fn SecretResolver) {
+	secretResolverMux.Lock()
+	defer secretResolverMux.Unlock()
+	if _, ok := secretResolvers[scheme]; ok {
+		panic(fmt.Errorf("secret resolver %s has been registered", scheme))
+	}
+	secretResolvers[scheme] = fn
+}
+
+func resolveFileSecret(ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func resolveEnvSecret(ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+// resolveSecret 如果value是已注册scheme的引用，则返回解析后的值，否则原样返回
+func resolveSecret(value string) (string, error) {
+	scheme, ref, ok := strings.Cut(value, "://")
+	if !ok {
+		return value, nil
+	}
+	secretResolverMux.RLock()
+	fn, ok := secretResolvers[scheme]
+	secretResolverMux.RUnlock()
+	if !ok {
+		return value, nil
+	}
+	resolved, err := fn(ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve secret %s:// failed: %w", scheme, err)
+	}
+	return resolved, nil
+}
+
+// newNamedFlagSets 创建使用resolveSecret解析被覆盖的敏感参数的参数集合
+func newNamedFlagSets() *flagx.NamedFlagSets {
+	nfs := flagx.NewNamedFlagSets()
+	nfs.SetSecretResolver(resolveSecret)
+	return nfs
+}
+
+// resolveSecretFlags 解析通过命令行参数、环境变量或者默认值设置的敏感参数引用
+func resolveSecretFlags() error {
+	var err error
+	nfs.VisitAll(func(p string, f *flag.Flag) {
+		key := f.Name
+		if p != "" {
+			key = p + "-" + f.Name
+		}
+		if !nfs.IsSecret(key) {
+			return
+		}
+		value := f.Value.String()
+		resolved, err2 := resolveSecret(value)
+		if err2 != nil {
+			err = fmt.Errorf("%s: %w", key, err2)
+			return
+		}
+		if resolved == value {
+			return
+		}
+		if err2 := nfs.Resolve(key, resolved); err2 != nil {
+			err = fmt.Errorf("%s: %w", key, err2)
+		}
+	})
+	return err
+}
@@ -0,0 +1,133 @@
+package box
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type secretRollbackServer[T any] struct {
+	Password string `flag:"password" default:"env://BOX_SECRET_ROLLBACK_PASSWORD" secret:"true"`
+	Port     int    `flag:"port" default:"80"`
+}
+
+func (s *secretRollbackServer[T]) Build(ctx context.Context) (*secretRollbackServer[T], error) {
+	return s, nil
+}
+
+type (
+	secretFromDefault struct{}
+	secretFromArgs    struct{}
+)
+
+func TestResolveSecretFlagsRollback(t *testing.T) {
+	t.Setenv("BOX_SECRET_ROLLBACK_PASSWORD", "env-password")
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("file-password\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		provide func() *string
+		args    []string
+		want    string
+	}{
+		{
+			name: "default",
+			provide: func() *string {
+				s := &secretRollbackServer[secretFromDefault]{}
+				Provide[*secretRollbackServer[secretFromDefault]](s, WithFlags("rollback"))
+				return &s.Password
+			},
+			want: "env-password",
+		},
+		{
+			name: "args",
+			provide: func() *string {
+				s := &secretRollbackServer[secretFromArgs]{}
+				Provide[*secretRollbackServer[secretFromArgs]](s, WithFlags("rollback"))
+				return &s.Password
+			},
+			args: []string{"--rollback-password=file://" + file},
+			want: "file-password",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			password := tt.provide()
+			bindFlags(t, tt.args...)
+			if err := resolveSecretFlags(); err != nil {
+				t.Fatal(err)
+			}
+			if *password != tt.want {
+				t.Fatalf("password = %s, want %s", *password, tt.want)
+			}
+			err := SetConfig([]ConfigItem{
+				{Key: "rollback-password", Value: "changed"},
+				{Key: "rollback-port", Value: "not-a-number"},
+			}, testSource(t))
+			var rejected *ConfigRejectedError
+			if !errors.As(err, &rejected) {
+				t.Fatalf("SetConfig error = %v, want *ConfigRejectedError", err)
+			}
+			if *password != tt.want {
+				t.Errorf("password after rollback = %s, want %s", *password, tt.want)
+			}
+		})
+	}
+}
+
+type secretShadowed struct{}
+
+// TestSecretRestoredFromShadowed 被覆盖的敏感参数引用在恢复生效时需要被解析
+func TestSecretRestoredFromShadowed(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("file-password\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	resetFlags(t)
+	s := &secretRollbackServer[secretShadowed]{}
+	Provide[*secretRollbackServer[secretShadowed]](s, WithFlags("shadowed"))
+	t.Setenv("BOX_SECRET_ROLLBACK_PASSWORD", "env-password")
+	bindFlags(t)
+	if err := resolveSecretFlags(); err != nil {
+		t.Fatal(err)
+	}
+
+	high, low := testSource(t, "high"), testSource(t, "low")
+	if err := SetConfig([]ConfigItem{{Key: "shadowed-password", Value: "high-password"}}, high); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetConfig([]ConfigItem{{Key: "shadowed-password", Value: "file://" + file}}, low); err != nil {
+		t.Fatal(err)
+	}
+	if s.Password != "high-password" {
+		t.Fatalf("password = %q, want high-password", s.Password)
+	}
+
+	if _, err := applyConfig([]configBatch{{removed: []string{"shadowed-password"}, source: high}}, high); err != nil {
+		t.Fatal(err)
+	}
+	if s.Password != "file-password" {
+		t.Errorf("password restored from shadowed = %q, want file-password", s.Password)
+	}
+
+	// 解析失败时删除被拒绝，保持原有的值
+	if err := SetConfig([]ConfigItem{{Key: "shadowed-password", Value: "high-password"}}, high); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetConfig([]ConfigItem{{Key: "shadowed-password", Value: "file://" + file + ".missing"}}, low); err != nil {
+		t.Fatal(err)
+	}
+	_, err := applyConfig([]configBatch{{removed: []string{"shadowed-password"}, source: high}}, high)
+	var rejected *ConfigRejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("remove with unresolvable shadowed secret = %v, want *ConfigRejectedError", err)
+	}
+	if s.Password != "high-password" {
+		t.Errorf("password after rejected remove = %q, want high-password", s.Password)
+	}
+}
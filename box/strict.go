@@ -0,0 +1,120 @@
+package box
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/daemtri/di/box/flagx"
+	"golang.org/x/exp/slog"
+)
+
+// UseStrictConfig 启用严格模式，所有配置加载器首次加载的配置中存在没有对应参数的key时，
+// Build会失败并返回包含所有未知key的 *UnknownKeysError，未启用时只记录警告日志
+func UseStrictConfig() BuildOption {
+	return buildOptionsFunc(func(o *buildOptions) {
+		o.strictConfig = true
+	})
+}
+
+// UnknownKey 配置来源中没有对应参数的key
+type UnknownKey struct {
+	Key    string
+	Source flagx.Source
+	// Suggestion 名称最接近的参数，没有相近的参数时为空
+	Suggestion string
+}
+
+func (uk UnknownKey) String() string {
+	if uk.Suggestion == "" {
+		return fmt.Sprintf("%s (source=%s)", uk.Key, uk.Source)
+	}
+	return fmt.Sprintf("%s (source=%s, did you mean %s?)", uk.Key, uk.Source, uk.Suggestion)
+}
+
+// UnknownKeysError 配置中存在没有对应参数的key
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+func (e *UnknownKeysError) Error() string {
+	keys := make([]string, 0, len(e.Keys))
+	for _, key := range e.Keys {
+		keys = append(keys, key.String())
+	}
+	return "未知的配置项: " + strings.Join(keys, "; ")
+}
+
+// unknownKeysError 没有未知的key时返回nil
+func unknownKeysError(keys []UnknownKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return &UnknownKeysError{Keys: keys}
+}
+
+// checkUnknownKeys 严格模式下存在未知key时返回 *UnknownKeysError，否则只记录警告日志
+func checkUnknownKeys(strict bool, keys []UnknownKey) error {
+	if strict {
+		return unknownKeysError(keys)
+	}
+	warnUnknownKeys(keys)
+	return nil
+}
+
+func warnUnknownKeys(keys []UnknownKey) {
+	for _, key := range keys {
+		slog.Warn("unknown config key", "key", key.Key, "source", key.Source, "suggestion", key.Suggestion)
+	}
+}
+
+// suggestKey 返回与key编辑距离最小的参数名称，距离超过key长度的三分之一（至少为2）时认为没有相近的参数
+func suggestKey(key string) string {
+	maxDistance := len(key) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	suggestion, best := "", maxDistance+1
+	nfs.VisitAll(func(prefix string, f *flag.Flag) {
+		name := f.Name
+		if prefix != "" {
+			name = prefix + "-" + f.Name
+		}
+		if d := editDistance(key, name); d < best {
+			suggestion, best = name, d
+		}
+	})
+	return suggestion
+}
+
+// editDistance 计算a和b之间的Levenshtein距离
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
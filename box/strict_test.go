@@ -0,0 +1,105 @@
+package box
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type (
+	txSuggest struct{}
+	txStrict  struct{}
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "host", b: "", want: 4},
+		{a: "host", b: "host", want: 0},
+		{a: "host", b: "hots", want: 2},
+		{a: "port", b: "prt", want: 1},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "地址", b: "地点", want: 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	resetFlags(t)
+	Provide[*txTestServer[txSuggest]](&txTestServer[txSuggest]{}, WithFlags("tx"))
+	bindFlags(t)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "tx-hots", want: "tx-host"},
+		{key: "tx_port", want: "tx-port"},
+		{key: "tx-label", want: "tx-labels"},
+		{key: "redis-addr", want: ""},
+	}
+	for _, tt := range tests {
+		if got := suggestKey(tt.key); got != tt.want {
+			t.Errorf("suggestKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestStrictConfig(t *testing.T) {
+	resetFlags(t)
+	server := &txTestServer[txStrict]{}
+	Provide[*txTestServer[txStrict]](server, WithFlags("tx"))
+	bindFlags(t)
+
+	source := testSource(t)
+	loader := &configLoaderBuilder{
+		ConfigLoader: &updatingTestLoader{updates: []ConfigUpdate{
+			{Items: []ConfigItem{{Key: "tx-host", Value: "file"}, {Key: "tx-hots", Value: "typo"}}},
+		}},
+		source: source,
+	}
+	unknown, err := loadConfig(context.Background(), loader)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	want := []UnknownKey{{Key: "tx-hots", Source: source, Suggestion: "tx-host"}}
+	if !reflect.DeepEqual(unknown, want) {
+		t.Errorf("unknown keys = %+v, want %+v", unknown, want)
+	}
+	// 未知的key不影响其他配置项生效
+	if server.Host != "file" {
+		t.Errorf("known key not applied: %s", server.Host)
+	}
+
+	if err := checkUnknownKeys(false, unknown); err != nil {
+		t.Errorf("checkUnknownKeys without strict mode: %v", err)
+	}
+	err = checkUnknownKeys(true, unknown)
+	var unknownErr *UnknownKeysError
+	if !errors.As(err, &unknownErr) || !reflect.DeepEqual(unknownErr.Keys, want) {
+		t.Fatalf("checkUnknownKeys in strict mode = %v, want *UnknownKeysError", err)
+	}
+	if !strings.Contains(err.Error(), "did you mean tx-host?") {
+		t.Errorf("error without suggestion: %v", err)
+	}
+	if err := checkUnknownKeys(true, nil); err != nil {
+		t.Errorf("checkUnknownKeys without unknown keys: %v", err)
+	}
+
+	err = SetConfig([]ConfigItem{{Key: "tx-prot", Value: "8080"}}, source)
+	if !errors.As(err, &unknownErr) || unknownErr.Keys[0].Suggestion != "tx-port" {
+		t.Errorf("SetConfig with unknown key = %v, want *UnknownKeysError", err)
+	}
+}
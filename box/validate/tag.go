@@ -2,6 +2,7 @@ package validate
 
 import (
 	"reflect"
+	"strings"
 )
 
 func isFlag(fieldTyp reflect.StructField) bool {
@@ -9,12 +10,13 @@ func isFlag(fieldTyp reflect.StructField) bool {
 	return ok
 }
 
-func parserTag(field reflect.StructField) (name, validate string) {
-	name, validate = field.Tag.Get("flag"), field.Tag.Get("validate")
+func parserTag(field reflect.StructField, tag string) (name, value string) {
+	name, value = field.Tag.Get("flag"), field.Tag.Get(tag)
 	return
 }
 
-func parseStruct(store map[string]string, prefix string, fType reflect.Type, fValue reflect.Value) {
+// parseStruct 遍历所有参数，fn的参数为参数所在结构体的前缀、参数完整名称以及tag标签的值
+func parseStruct(tag string, prefix string, fType reflect.Type, fValue reflect.Value, fn func(prefix, name, value string)) {
 	if fType.Kind() == reflect.Ptr {
 		fType = fType.Elem()
 		fValue = fValue.Elem()
@@ -34,7 +36,7 @@ func parseStruct(store map[string]string, prefix string, fType reflect.Type, fVa
 			fieldValue = fieldValue.Elem()
 			fieldType = fieldValue.Type()
 		}
-		name, validate := parserTag(field)
+		name, value := parserTag(field, tag)
 		if prefix != "" {
 			if name == "" {
 				name = prefix
@@ -43,17 +45,42 @@ func parseStruct(store map[string]string, prefix string, fType reflect.Type, fVa
 			}
 		}
 		if fieldType.Kind() == reflect.Struct || (fieldType.Kind() == reflect.Ptr && fieldType.Elem().Kind() == reflect.Struct) {
-			parseStruct(store, name, fieldType, fieldValue)
+			parseStruct(tag, name, fieldType, fieldValue, fn)
 			continue
 		}
-		if validate != "" {
-			store[name] = validate
+		if value != "" {
+			fn(prefix, name, value)
 		}
 	}
 }
 
 func ParseValidateString(prefix string, v any) map[string]string {
+	return ParseTagString(prefix, v, "validate")
+}
+
+// ParseTagString 返回v中所有参数的完整名称及其tag标签的值，没有tag标签的参数会被忽略
+func ParseTagString(prefix string, v any, tag string) map[string]string {
+	store := make(map[string]string)
+	parseStruct(tag, prefix, reflect.TypeOf(v), reflect.ValueOf(v), func(_, name, value string) {
+		store[name] = value
+	})
+	return store
+}
+
+// ParseAliases 解析v中所有参数的alias标签，返回别名的完整名称到参数完整名称的映射，
+// 别名与参数位于同一个前缀下，多个别名使用逗号分隔，如 `flag:"addr" alias:"address,host"`
+func ParseAliases(prefix string, v any) map[string]string {
 	store := make(map[string]string)
-	parseStruct(store, prefix, reflect.TypeOf(v), reflect.ValueOf(v))
+	parseStruct("alias", prefix, reflect.TypeOf(v), reflect.ValueOf(v), func(pfx, name, value string) {
+		for _, alias := range strings.Split(value, ",") {
+			if alias = strings.TrimSpace(alias); alias == "" {
+				continue
+			}
+			if pfx != "" {
+				alias = pfx + "-" + alias
+			}
+			store[alias] = name
+		}
+	})
 	return store
 }
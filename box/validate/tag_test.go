@@ -24,3 +24,22 @@ func Test_ParseValidateString(t *testing.T) {
 		t.Fatal("test failed", ret)
 	}
 }
+
+func Test_ParseAliases(t *testing.T) {
+	type A struct {
+		Addr string `flag:"addr" alias:"address, host"`
+		Port int    `flag:"port"`
+	}
+	type B struct {
+		Timeout int `flag:"dial-timeout" alias:"timeout"`
+		A       `flag:"a"`
+	}
+	ret := ParseAliases("b", &B{})
+	if !reflect.DeepEqual(ret, map[string]string{
+		"b-timeout":   "b-dial-timeout",
+		"b-a-address": "b-a-addr",
+		"b-a-host":    "b-a-addr",
+	}) {
+		t.Fatal("test failed", ret)
+	}
+}
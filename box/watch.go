@@ -0,0 +1,171 @@
+package box
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/daemtri/di"
+	"github.com/daemtri/di/box/flagx"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	subscribers   = map[int]*subscriber{}
+	subscriberSeq int
+	subscriberMux sync.Mutex
+)
+
+type subscriber struct {
+	prefix string
+	fn     func(changes []Change)
+}
+
+// match 判断key是否属于prefix，prefix为空时匹配所有key
+func (s *subscriber) match(key string) bool {
+	return s.prefix == "" || key == s.prefix || strings.HasPrefix(key, s.prefix+"-")
+}
+
+// OnConfigChange 订阅参数变更，prefix为参数名称的前缀，通常为WithFlags指定的prefix，为空时订阅所有参数
+// 每次SetConfig成功后，如果有prefix下的参数发生变更，则使用这些变更调用fn，返回的函数用于取消订阅
+func OnConfigChange(prefix string, fn func(changes []Change)) (cancel func()) {
+	subscriberMux.Lock()
+	defer subscriberMux.Unlock()
+	subscriberSeq++
+	id := subscriberSeq
+	subscribers[id] = &subscriber{prefix: strings.TrimSuffix(prefix, "-"), fn: fn}
+	return func() {
+		subscriberMux.Lock()
+		defer subscriberMux.Unlock()
+		delete(subscribers, id)
+	}
+}
+
+func notifyConfigChange(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+	subscriberMux.Lock()
+	matched := make([]func(), 0, len(subscribers))
+	for _, s := range subscribers {
+		var subChanges []Change
+		for i := range changes {
+			if s.match(changes[i].Key) {
+				subChanges = append(subChanges, changes[i])
+			}
+		}
+		if len(subChanges) > 0 {
+			fn := s.fn
+			matched = append(matched, func() { fn(subChanges) })
+		}
+	}
+	subscriberMux.Unlock()
+	for i := range matched {
+		matched[i]()
+	}
+}
+
+// optionPrefixes 记录参数结构体的类型对应的WithFlags前缀，用于Watch查找参数结构体的前缀
+var optionPrefixes = map[reflect.Type][]string{}
+
+// registerOptionPrefix 记录builder以及被其包装的参数结构体使用的前缀
+func registerOptionPrefix(builder any, prefix string) {
+	for _, target := range retrofitTargets(builder) {
+		typ := reflect.TypeOf(target)
+		if typ == nil {
+			continue
+		}
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if !slices.Contains(optionPrefixes[typ], prefix) {
+			optionPrefixes[typ] = append(optionPrefixes[typ], prefix)
+		}
+	}
+}
+
+// Watch 监听参数结构体T的变更，每次T绑定的参数发生变更时，使用当前的参数值创建一个新的T并发送到返回的channel中，
+// T为Provide时使用WithFlags绑定了参数的参数结构体（或builder）及其指针，前缀为WithFlags指定的前缀，
+// 如果T没有绑定参数，或者被绑定到了多个前缀，会引发Panic，此时需要使用WatchPrefix指定前缀，
+// channel只保留最新的值，ctx结束时channel会被关闭
+func Watch[T any](ctx context.Context) <-chan T {
+	typ := reflectType[T]()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	prefixes := optionPrefixes[typ]
+	if len(prefixes) != 1 {
+		panic(fmt.Errorf("Watch[%s]需要T使用WithFlags绑定到唯一的前缀，当前前缀: %v，请使用WatchPrefix", typ, prefixes))
+	}
+	return WatchPrefix[T](ctx, prefixes[0])
+}
+
+// WatchPrefix 监听prefix下的参数变更，每次变更时使用当前的参数值创建一个新的T并发送到返回的channel中，
+// T为参数结构体或者参数结构体的指针，其flag标签与使用WithFlags(prefix)提供时的结构体一致，
+// channel只保留最新的值，ctx结束时channel会被关闭
+func WatchPrefix[T any](ctx context.Context, prefix string) <-chan T {
+	ch := make(chan T, 1)
+	var mux sync.Mutex
+	closed := false
+	cancel := OnConfigChange(prefix, func(changes []Change) {
+		opt, err := newOptionFromFlags[T](prefix)
+		if err != nil {
+			slog.Error("watch config failed", "prefix", prefix, "error", err)
+			return
+		}
+		mux.Lock()
+		defer mux.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- opt
+	})
+	go func() {
+		<-ctx.Done()
+		cancel()
+		mux.Lock()
+		defer mux.Unlock()
+		closed = true
+		close(ch)
+	}()
+	return ch
+}
+
+// newOptionFromFlags 创建一个T，并使用prefix下参数的当前值填充
+func newOptionFromFlags[T any](prefix string) (T, error) {
+	typ := reflectType[T]()
+	ptr := reflect.New(typ)
+	target := ptr.Interface()
+	if typ.Kind() == reflect.Pointer {
+		ptr.Elem().Set(reflect.New(typ.Elem()))
+		target = ptr.Elem().Interface()
+	}
+	fs := flag.NewFlagSet(prefix, flag.ContinueOnError)
+	di.AddFlags(fs, target)
+	// 读取参数的同时可能有SetConfig正在修改参数
+	configMux.Lock()
+	defer configMux.Unlock()
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		key := f.Name
+		if prefix != "" {
+			key = prefix + "-" + f.Name
+		}
+		current := nfs.Lookup(key)
+		if current == nil {
+			return
+		}
+		if err2 := flagx.SetValue(f, current.Value.String()); err2 != nil && err == nil {
+			err = fmt.Errorf("set %s failed: %w", key, err2)
+		}
+	})
+	return ptr.Elem().Interface().(T), err
+}
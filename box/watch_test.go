@@ -0,0 +1,97 @@
+package box
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type watchTestClient struct{}
+
+type watchTestOption struct {
+	Addr string `flag:"addr" default:"localhost"`
+	Port int    `flag:"port" default:"80"`
+}
+
+func newWatchTestClient(opt *watchTestOption) (*watchTestClient, error) {
+	return &watchTestClient{}, nil
+}
+
+type watchSubscribed struct{}
+
+func TestOnConfigChange(t *testing.T) {
+	resetFlags(t)
+	Provide[*txTestServer[watchSubscribed]](&txTestServer[watchSubscribed]{}, WithFlags("watch-sub"))
+	FlagSet("watch-other").String("addr", "", "")
+	bindFlags(t)
+
+	var got []Change
+	cancel := OnConfigChange("watch-sub", func(changes []Change) {
+		got = append(got, changes...)
+	})
+	source := testSource(t)
+	if err := SetConfig([]ConfigItem{
+		{Key: "watch-sub-host", Value: "example.com"},
+		{Key: "watch-other-addr", Value: "other"},
+	}, source); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != (Change{Key: "watch-sub-host", Old: "localhost", New: "example.com", Source: source}) {
+		t.Errorf("unexpected changes: %+v", got)
+	}
+
+	cancel()
+	if err := SetConfig([]ConfigItem{{Key: "watch-sub-port", Value: "81"}}, source); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("notified after cancel: %+v", got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	resetFlags(t)
+	Provide[*watchTestClient](newWatchTestClient, WithFlags("watch"))
+	bindFlags(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := Watch[*watchTestOption](ctx)
+	values := WatchPrefix[watchTestOption](ctx, "watch")
+	if err := SetConfig([]ConfigItem{{Key: "watch-port", Value: "81"}}, testSource(t)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case opt := <-ch:
+		if opt.Addr != "localhost" || opt.Port != 81 {
+			t.Errorf("unexpected option: %+v", opt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no option received")
+	}
+	if opt := <-values; opt.Port != 81 {
+		t.Errorf("unexpected option: %+v", opt)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("unexpected option after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
+
+type watchTestUnbound struct {
+	Addr string `flag:"addr"`
+}
+
+func TestWatchUnbound(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for an option without WithFlags")
+		}
+	}()
+	Watch[*watchTestUnbound](context.Background())
+}
@@ -2,6 +2,7 @@ package di
 
 import (
 	"context"
+	"flag"
 	"reflect"
 	"sync"
 )
@@ -9,6 +10,13 @@ import (
 type constructor struct {
 	builder  any
 	instance any
+	// target is the object that dependencies are injected into,
+	// it is the builder itself unless the builder implements InjectTarget
+	target     any
+	injections []injection
+
+	// flags are the flags added by the builder, keyed by full flag name
+	flags map[string]*flag.Flag
 
 	validateFlagsFunc func() error
 	buildFunc         func(ctx context.Context) (any, error)
@@ -20,20 +28,61 @@ type constructor struct {
 	mux sync.RWMutex
 }
 
+// injectTargeter can be implemented by a builder to inject dependencies
+// into another object instead of the builder itself.
+type injectTargeter interface {
+	InjectTarget() any
+}
+
+func injectTargetOf(builder any) any {
+	if it, ok := builder.(injectTargeter); ok {
+		return it.InjectTarget()
+	}
+	return builder
+}
+
+// addFlags adds the flags of sf into fs and records them
+func (c *constructor) addFlags(sf *structFlagger, fs *flag.FlagSet) {
+	existing := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) {
+		existing[f.Name] = true
+	})
+	sf.AddFlags(fs)
+	c.flags = make(map[string]*flag.Flag)
+	fs.VisitAll(func(f *flag.Flag) {
+		if existing[f.Name] {
+			return
+		}
+		name := f.Name
+		if fs.Name() != "" {
+			name = fs.Name() + "-" + f.Name
+		}
+		c.flags[name] = f
+	})
+}
+
 func (c *constructor) validateFlags() error {
 	return c.validateFlagsFunc()
 }
 
+// loadInstance returns the built instance, or nil if the constructor has not been built
+func (c *constructor) loadInstance() any {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.instance
+}
+
 func (c *constructor) build(ctx context.Context) (any, error) {
-	if c.instance != nil {
-		return c.instance, nil
+	if instance := c.loadInstance(); instance != nil {
+		return instance, nil
 	}
 	if c.mux.TryLock() {
 		defer c.mux.Unlock()
-		if err := getContext(ctx).container().inject(ctx, c); err != nil {
-			return nil, err
+		// the instance may be built by another goroutine before the lock is acquired
+		if c.instance != nil {
+			return c.instance, nil
 		}
-		result, err := c.buildFunc(ctx)
+		result, err := c.create(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -44,3 +93,25 @@ func (c *constructor) build(ctx context.Context) (any, error) {
 	}
 	return c.instance, nil
 }
+
+// rebuild builds the instance again even if it has been built and returns the previous one.
+// The lock is held while rebuilding, so concurrent builds wait for and get the new instance
+// instead of building it again. The previous instance is kept if the rebuilding failed.
+func (c *constructor) rebuild(ctx context.Context) (old any, rtn any, err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	result, err := c.create(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	old, c.instance = c.instance, result
+	return old, result, nil
+}
+
+// create injects the dependencies and calls the builder, the caller must hold the lock
+func (c *constructor) create(ctx context.Context) (any, error) {
+	if err := getContext(ctx).container().inject(ctx, c); err != nil {
+		return nil, err
+	}
+	return c.buildFunc(ctx)
+}
@@ -82,6 +82,13 @@ func (c *container) validateFlags() error {
 }
 
 func (c *container) build(ctx context.Context, typ reflect.Type, name string) (any, error) {
+	return c.buildWith(ctx, typ, name, (*constructor).build)
+}
+
+// buildWith checks the requirer context and the flags of the constructor of typ and name,
+// then builds it with the build function
+func (c *container) buildWith(ctx context.Context, typ reflect.Type, name string,
+	build func(cst *constructor, ctx context.Context) (any, error)) (any, error) {
 	localCtx := getContext(ctx)
 	if localCtx.isDiscard() {
 		return nil, fmt.Errorf("cannot build %s outside of constructor, Context is invalid", typ)
@@ -109,32 +116,37 @@ func (c *container) build(ctx context.Context, typ reflect.Type, name string) (a
 	if err := cst.validateFlags(); err != nil {
 		return nil, fmt.Errorf("validate flags error: %w", err)
 	}
-	rtn, err := cst.build(withContext(ctx, newLocalCtx))
+	rtn, err := build(cst, withContext(ctx, newLocalCtx))
 	if err != nil {
 		return nil, fmt.Errorf("build type %s error: %w", typ, err)
 	}
 	return rtn, nil
 }
 
-func (c *container) exists(ctx context.Context, p reflect.Type) bool {
+func (c *container) existsNamed(p reflect.Type, name string) bool {
 	s, ok := c.constructors[p]
 	if !ok {
 		return false
 	}
-	localCtx := getContext(ctx)
-	return s.exists(localCtx.requirer().constructor.selections[p])
+	return s.exists(name)
 }
 
-func (c *container) mustAll(ctx context.Context, p reflect.Type) map[string]any {
-	targetType := p
+// targetType returns the implementation type of p specified by WithImplement,
+// or p itself if there is none.
+func (c *container) targetType(ctx context.Context, p reflect.Type) reflect.Type {
 	if p.Kind() == reflect.Interface {
 		if iType := getImplementFromContext(ctx, p); iType != nil {
-			targetType = iType
+			return iType
 		}
 	}
+	return p
+}
+
+func (c *container) all(ctx context.Context, p reflect.Type) (map[string]any, error) {
+	targetType := c.targetType(ctx, p)
 	cst, ok := c.constructors[targetType]
 	if !ok {
-		panic(fmt.Errorf("the type %s does not exist", reflectTypeString(targetType)))
+		return nil, fmt.Errorf("the type %s does not exist", reflectTypeString(targetType))
 	}
 	vv := make(map[string]any, len(cst.groups))
 	optionalFunc := getOptionalFuncFromContext(ctx, p)
@@ -145,65 +157,54 @@ func (c *container) mustAll(ctx context.Context, p reflect.Type) map[string]any
 				optionalFunc(name, err)
 				continue
 			}
-			panic(fmt.Errorf("invoke build failed: %s, requirer: %s", err, getContext(ctx).Path()))
+			return nil, fmt.Errorf("invoke build failed: %s, requirer: %s", err, getContext(ctx).Path())
 		}
 		vv[name] = v
 	}
 
+	return vv, nil
+}
+
+func (c *container) mustAll(ctx context.Context, p reflect.Type) map[string]any {
+	vv, err := c.all(ctx, p)
+	if err != nil {
+		panic(err)
+	}
 	return vv
 }
 
 func (c *container) must(ctx context.Context, p reflect.Type) any {
-	targetType := p
-	if p.Kind() == reflect.Interface {
-		if iType := getImplementFromContext(ctx, p); iType != nil {
-			targetType = iType
-		}
-	}
-	name := getTypeNameFromContext(ctx, targetType)
-	v, err := c.build(ctx, p, name)
+	v, err := c.buildOrOptional(ctx, p, getTypeNameFromContext(ctx, c.targetType(ctx, p)))
 	if err != nil {
-		optionalFunc := getOptionalFuncFromContext(ctx, p)
-		if optionalFunc != nil {
-			optionalFunc(name, err)
-			return nil
-		}
 		panic(fmt.Errorf("invoke build failed: %s, requirer: %s", err, getContext(ctx).Path()))
 	}
 	return v
 }
 
-func (c *container) inject(ctx context.Context, cst *constructor) error {
-	refTyp := reflect.TypeOf(cst.builder)
-	refVal := reflect.ValueOf(cst.builder)
-	if refTyp.Kind() == reflect.Pointer {
-		refTyp = refTyp.Elem()
-		refVal = refVal.Elem()
-	}
-	if refTyp.Kind() != reflect.Struct {
-		return nil
+// buildOrOptional builds the named p, if the building failed and the requirer
+// specified WithOptional for p, the error is passed to the optional function and nil is returned.
+func (c *container) buildOrOptional(ctx context.Context, p reflect.Type, name string) (any, error) {
+	v, err := c.build(ctx, p, name)
+	if err != nil {
+		if optionalFunc := getOptionalFuncFromContext(ctx, p); optionalFunc != nil {
+			optionalFunc(name, err)
+			return nil, nil
+		}
+		return nil, err
 	}
+	return v, nil
+}
 
-	for i := 0; i < refTyp.NumField(); i++ {
-		if !refVal.Field(i).CanSet() {
-			continue
-		}
-		injectType, ok := refTyp.Field(i).Tag.Lookup("inject")
-		if !ok {
-			continue
-		}
-		if injectType == "must" {
-			v := c.must(ctx, refTyp.Field(i).Type)
-			refVal.Field(i).Set(reflect.ValueOf(v))
-			continue
-		}
-		if injectType == "exists" {
-			if c.exists(ctx, refTyp.Field(i).Type) {
-				v := c.must(ctx, refTyp.Field(i).Type)
-				refVal.Field(i).Set(reflect.ValueOf(v))
-			}
-			continue
-		}
+// rebuild builds the constructor of typ and name again, even if it has been built.
+// the previous instance is kept if the rebuilding failed.
+func (c *container) rebuild(ctx context.Context, typ reflect.Type, name string) (old any, rtn any, err error) {
+	rtn, err = c.buildWith(ctx, typ, name, func(cst *constructor, ctx context.Context) (any, error) {
+		prev, rtn, err := cst.rebuild(ctx)
+		old = prev
+		return rtn, err
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil
+	return old, rtn, nil
 }
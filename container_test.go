@@ -0,0 +1,72 @@
+package di
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type containerTestClient struct {
+	id int32
+}
+
+func TestRebuildConcurrentBuild(t *testing.T) {
+	r := NewRegistry()
+	var builds atomic.Int32
+	rebuilding, release := make(chan struct{}), make(chan struct{})
+	typ := reflectType[*containerTestClient]()
+	r.Provide(typ, &containerTestClient{}, func(ctx context.Context) (any, error) {
+		id := builds.Add(1)
+		if id == 2 {
+			close(rebuilding)
+			<-release
+		}
+		return &containerTestClient{id: id}, nil
+	})
+	ctx := withContext(context.Background(), newBaseContext(r.container))
+	if _, err := r.build(ctx, typ, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		old   any
+		rtn   any
+		err   error
+		built [4]any
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		old, rtn, err = r.Rebuild(context.Background(), typ, "")
+	}()
+	<-rebuilding
+	// builds during rebuilding wait for the new instance instead of building another one
+	for i := range built {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			built[i], _ = r.build(withContext(context.Background(), newBaseContext(r.container)), typ, "")
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if old.(*containerTestClient).id != 1 || rtn.(*containerTestClient).id != 2 {
+		t.Errorf("unexpected rebuild result: old=%+v, new=%+v", old, rtn)
+	}
+	for i, v := range built {
+		if v != rtn {
+			t.Errorf("build %d during rebuilding got %+v, want %+v", i, v, rtn)
+		}
+	}
+	if n := builds.Load(); n != 2 {
+		t.Errorf("built %d times, want 2", n)
+	}
+}
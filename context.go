@@ -85,22 +85,9 @@ func (rc *requirerContext) isDiscard() bool {
 }
 
 func (rc *requirerContext) Invoke(ctx context.Context, typ reflect.Type) any {
-	if typ.Kind() == reflect.Map {
-		elemTyp := typ.Elem()
-		allValues := rc.container().mustAll(ctx, elemTyp)
-		all := reflect.MakeMap(typ)
-		for name := range allValues {
-			all.SetMapIndex(reflect.ValueOf(name), reflect.ValueOf(allValues[name]))
-		}
-		return all.Interface()
-	} else if typ.Kind() == reflect.Slice {
-		elemTyp := typ.Elem()
-		allValues := rc.container().mustAll(ctx, elemTyp)
-		all := reflect.MakeSlice(typ, 0, len(allValues))
-		for _, value := range allValues {
-			all = reflect.Append(all, reflect.ValueOf(value))
-		}
-		return all.Interface()
+	if typ.Kind() == reflect.Map || typ.Kind() == reflect.Slice {
+		allValues := rc.container().mustAll(ctx, typ.Elem())
+		return makeGroup(typ, allValues).Interface()
 	}
 	return rc.container().must(ctx, typ)
 }
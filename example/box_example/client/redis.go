@@ -12,7 +12,7 @@ type RedisOptions struct {
 	Port     int    `flag:"port" default:"6379" usage:"redis服务端口"`
 	DB       int    `flag:"db" default:"0" usage:"redis db"`
 	User     string `flag:"user" default:"root" usage:"redis用户名"`
-	Password string `flag:"password" default:"xxx" usage:"redis密码"`
+	Password string `flag:"password" default:"xxx" usage:"redis密码" secret:"true"`
 }
 
 type RedisClient struct {
@@ -148,6 +148,12 @@ func parseStruct(fs *flag.FlagSet, pfx prefix, fType reflect.Type, fValue reflec
 	}
 }
 
+// AddFlags binds the fields tagged with `flag` of options to fs,
+// options must be a pointer to struct, or implement AddFlags(fs *flag.FlagSet).
+func AddFlags(fs *flag.FlagSet, options any) {
+	newStructFlagger(options).AddFlags(fs)
+}
+
 type structFlagger struct {
 	options any
 
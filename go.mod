@@ -3,8 +3,10 @@ module github.com/daemtri/di
 go 1.20
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/go-playground/validator/v10 v10.12.0
 	github.com/joho/godotenv v1.5.1
+	github.com/shima-park/agollo v1.2.14
 	github.com/tidwall/gjson v1.14.4
 	github.com/tidwall/sjson v1.2.5
 	golang.org/x/exp v0.0.0-20230321023759-10a507213a29
@@ -18,7 +20,6 @@ require (
 	github.com/kr/pretty v0.1.0 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/leodido/go-urn v1.2.2 // indirect
-	github.com/shima-park/agollo v1.2.14 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
 	golang.org/x/crypto v0.7.0 // indirect
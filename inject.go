@@ -0,0 +1,212 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// injection describes how a field tagged with `inject` is filled.
+//
+// The tag value is a comma separated list of options:
+//
+//	must          the dependency must exist and be built successfully (default)
+//	exists        alias of optional, kept for compatibility
+//	optional      leave the field as zero value if the dependency is not provided
+//	group         the field is a slice or map, filled with all instances of the element type
+//	lazy          the field is a func() T or func() (T, error), the dependency is built on the first call
+//	name=<name>   inject the dependency with the given name, instead of the one selected by WithSelect
+//
+// e.g. `inject:"name=replica,optional"`, `inject:"group"`, `inject:"lazy"`
+type injection struct {
+	index    int
+	field    string
+	typ      reflect.Type
+	name     string
+	hasName  bool
+	optional bool
+	group    bool
+	lazy     bool
+}
+
+func parseInjectTag(field reflect.StructField, tag string) (injection, error) {
+	ij := injection{index: field.Index[0], field: field.Name, typ: field.Type}
+	must := false
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "" || opt == "must":
+			must = true
+		case opt == "exists" || opt == "optional":
+			ij.optional = true
+		case opt == "group":
+			ij.group = true
+		case opt == "lazy":
+			ij.lazy = true
+		case strings.HasPrefix(opt, "name="):
+			ij.name = strings.TrimPrefix(opt, "name=")
+			ij.hasName = true
+		default:
+			return ij, fmt.Errorf("unknown inject option %q", opt)
+		}
+	}
+	if must && ij.optional {
+		return ij, fmt.Errorf("inject options must and optional are exclusive")
+	}
+	if ij.group {
+		if ij.hasName || ij.lazy {
+			return ij, fmt.Errorf("inject option group can not be used with name or lazy")
+		}
+		switch field.Type.Kind() {
+		case reflect.Slice:
+		case reflect.Map:
+			if field.Type.Key().Kind() != reflect.String {
+				return ij, fmt.Errorf("inject option group requires map key to be string, got %s", field.Type)
+			}
+		default:
+			return ij, fmt.Errorf("inject option group requires slice or map type, got %s", field.Type)
+		}
+	} else if ij.lazy {
+		if field.Type.Kind() != reflect.Func || field.Type.NumIn() != 0 ||
+			field.Type.NumOut() < 1 || field.Type.NumOut() > 2 ||
+			(field.Type.NumOut() == 2 && field.Type.Out(1) != errorType) {
+			return ij, fmt.Errorf("inject option lazy requires func() T or func() (T, error), got %s", field.Type)
+		}
+	} else if field.Type.Kind() == reflect.Slice || field.Type.Kind() == reflect.Map {
+		return ij, fmt.Errorf("inject slice or map type %s requires group option", field.Type)
+	}
+	return ij, nil
+}
+
+// parseInjections parses all fields tagged with `inject` of the target,
+// target must be a struct or a pointer to struct, otherwise nothing will be injected.
+func parseInjections(target any) ([]injection, error) {
+	refTyp := reflect.TypeOf(target)
+	if refTyp == nil {
+		return nil, nil
+	}
+	if refTyp.Kind() == reflect.Pointer {
+		refTyp = refTyp.Elem()
+	}
+	if refTyp.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	var injections []injection
+	for i := 0; i < refTyp.NumField(); i++ {
+		field := refTyp.Field(i)
+		tag, ok := field.Tag.Lookup("inject")
+		if !ok {
+			continue
+		}
+		if !field.IsExported() {
+			return nil, fmt.Errorf("field %s.%s with inject tag must be exported", refTyp, field.Name)
+		}
+		ij, err := parseInjectTag(field, tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s has invalid inject tag %q: %w", refTyp, field.Name, tag, err)
+		}
+		injections = append(injections, ij)
+	}
+	return injections, nil
+}
+
+func (c *container) inject(ctx context.Context, cst *constructor) error {
+	if len(cst.injections) == 0 {
+		return nil
+	}
+	refVal := reflect.ValueOf(cst.target)
+	if refVal.Kind() == reflect.Pointer {
+		refVal = refVal.Elem()
+	}
+	for _, ij := range cst.injections {
+		v, err := c.injectValue(ctx, ij)
+		if err != nil {
+			return fmt.Errorf("inject field %s error: %w", ij.field, err)
+		}
+		if v.IsValid() {
+			refVal.Field(ij.index).Set(v)
+		}
+	}
+	return nil
+}
+
+// injectValue returns the value to be injected, an invalid value means the field should be left untouched.
+func (c *container) injectValue(ctx context.Context, ij injection) (reflect.Value, error) {
+	if ij.group {
+		elemTyp := ij.typ.Elem()
+		if _, ok := c.constructors[c.targetType(ctx, elemTyp)]; ij.optional && !ok {
+			return reflect.Value{}, nil
+		}
+		values, err := c.all(ctx, elemTyp)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return makeGroup(ij.typ, values), nil
+	}
+
+	typ := ij.typ
+	if ij.lazy {
+		typ = ij.typ.Out(0)
+	}
+	name := ij.name
+	if !ij.hasName {
+		name = getTypeNameFromContext(ctx, c.targetType(ctx, typ))
+	}
+	if ij.optional && !c.existsNamed(typ, name) {
+		return reflect.Value{}, nil
+	}
+	if ij.lazy {
+		return c.lazyValue(ctx, ij.typ, name), nil
+	}
+	v, err := c.buildOrOptional(ctx, typ, name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if v == nil {
+		return reflect.Value{}, nil
+	}
+	return reflect.ValueOf(v), nil
+}
+
+// lazyValue creates a function which builds the dependency on the first call.
+// the function is called after the constructor is built, so it uses a new root context.
+func (c *container) lazyValue(ctx context.Context, fnTyp reflect.Type, name string) reflect.Value {
+	typ := fnTyp.Out(0)
+	return reflect.MakeFunc(fnTyp, func([]reflect.Value) []reflect.Value {
+		v, err := c.build(withContext(ctx, newBaseContext(c)), typ, name)
+		ret := reflect.New(typ).Elem()
+		if err == nil && v != nil {
+			ret.Set(reflect.ValueOf(v))
+		}
+		if fnTyp.NumOut() == 1 {
+			if err != nil {
+				panic(fmt.Errorf("lazy build %s (name=[%s]) failed: %w", typ, name, err))
+			}
+			return []reflect.Value{ret}
+		}
+		errVal := reflect.New(errorType).Elem()
+		if err != nil {
+			errVal.Set(reflect.ValueOf(err))
+		}
+		return []reflect.Value{ret, errVal}
+	})
+}
+
+// makeGroup converts all instances into a slice or map of typ
+func makeGroup(typ reflect.Type, values map[string]any) reflect.Value {
+	if typ.Kind() == reflect.Map {
+		all := reflect.MakeMapWithSize(typ, len(values))
+		for name := range values {
+			all.SetMapIndex(reflect.ValueOf(name).Convert(typ.Key()), reflect.ValueOf(values[name]))
+		}
+		return all
+	}
+	all := reflect.MakeSlice(typ, 0, len(values))
+	for _, value := range values {
+		all = reflect.Append(all, reflect.ValueOf(value))
+	}
+	return all
+}
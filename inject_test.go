@@ -0,0 +1,151 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type injectTestDB struct {
+	name string
+}
+
+type injectTestService struct {
+	Primary *injectTestDB                 `inject:"must"`
+	Replica *injectTestDB                 `inject:"name=replica"`
+	Missing *injectTestMissing            `inject:"optional"`
+	All     []*injectTestDB               `inject:"group"`
+	ByName  map[string]*injectTestDB      `inject:"group"`
+	Lazy    func() (*injectTestDB, error) `inject:"lazy,name=replica"`
+}
+
+type injectTestMissing struct{}
+
+func Test_parseInjections(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  any
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			target: &injectTestService{},
+		},
+		{
+			name: "unknown option",
+			target: &struct {
+				DB *injectTestDB `inject:"maybe"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "must and optional",
+			target: &struct {
+				DB *injectTestDB `inject:"must,optional"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "group without slice",
+			target: &struct {
+				DB *injectTestDB `inject:"group"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "slice without group",
+			target: &struct {
+				DB []*injectTestDB `inject:"must"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "lazy without func",
+			target: &struct {
+				DB *injectTestDB `inject:"lazy"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "unexported",
+			target: &struct {
+				db *injectTestDB `inject:"must"`
+			}{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseInjections(tt.target); (err != nil) != tt.wantErr {
+				t.Errorf("parseInjections() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func (s *injectTestService) Build(ctx context.Context) (*injectTestService, error) {
+	return s, nil
+}
+
+func TestInject(t *testing.T) {
+	r := NewRegistry()
+	dbTyp := reflectType[*injectTestDB]()
+	for _, name := range []string{"", "replica"} {
+		db := &injectTestDB{name: name}
+		r.Provide(dbTyp, db, func(ctx context.Context) (any, error) { return db, nil }, WithName(name))
+	}
+	b := &injectTestService{}
+	r.Provide(reflectType[*injectTestService](), b, func(ctx context.Context) (any, error) {
+		return b.Build(ctx)
+	})
+
+	ctx := withContext(context.Background(), newBaseContext(r.container))
+	v, err := r.build(ctx, reflectType[*injectTestService](), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := v.(*injectTestService)
+	if svc.Primary.name != "" || svc.Replica.name != "replica" || svc.Missing != nil {
+		t.Fatalf("unexpected injection: %+v", svc)
+	}
+	if len(svc.All) != 2 || !reflect.DeepEqual(svc.ByName["replica"], svc.Replica) {
+		t.Fatalf("unexpected group injection: %+v", svc)
+	}
+	lazy, err := svc.Lazy()
+	if err != nil || lazy != svc.Replica {
+		t.Fatalf("unexpected lazy injection: %v, %v", lazy, err)
+	}
+}
+
+type injectTestCache struct{}
+
+type injectTestOptionalService struct {
+	Cache *injectTestCache `inject:"must"`
+}
+
+func TestInjectMustWithOptional(t *testing.T) {
+	r := NewRegistry()
+	r.Provide(reflectType[*injectTestCache](), &injectTestCache{}, func(ctx context.Context) (any, error) {
+		return nil, errors.New("cache unavailable")
+	})
+	var optionalErr error
+	b := &injectTestOptionalService{}
+	r.Provide(reflectType[*injectTestOptionalService](), b, func(ctx context.Context) (any, error) {
+		return b, nil
+	}, WithOptional[*injectTestCache](func(name string, err error) {
+		optionalErr = err
+	}))
+
+	ctx := withContext(context.Background(), newBaseContext(r.container))
+	v, err := r.build(ctx, reflectType[*injectTestOptionalService](), "")
+	if err != nil {
+		t.Fatalf("must field provided by WithOptional should not fail: %v", err)
+	}
+	if svc := v.(*injectTestOptionalService); svc.Cache != nil {
+		t.Errorf("unexpected injection: %+v", svc)
+	}
+	if optionalErr == nil {
+		t.Errorf("optional function is not called with the build error")
+	}
+}
@@ -0,0 +1,37 @@
+package di
+
+import (
+	"sync/atomic"
+)
+
+// Live holds an instance which can be replaced atomically when it is rebuilt.
+// Consumers should hold the Live handle and call Load every time they use the instance,
+// instead of keeping the instance itself.
+type Live[T any] struct {
+	value atomic.Pointer[T]
+}
+
+// NewLive creates a Live handle holding v
+func NewLive[T any](v T) *Live[T] {
+	l := &Live[T]{}
+	l.value.Store(&v)
+	return l
+}
+
+// Load returns the current instance
+func (l *Live[T]) Load() T {
+	v := l.value.Load()
+	if v == nil {
+		return emptyValue[T]()
+	}
+	return *v
+}
+
+// Swap replaces the current instance with v and returns the previous one
+func (l *Live[T]) Swap(v T) T {
+	old := l.value.Swap(&v)
+	if old == nil {
+		return emptyValue[T]()
+	}
+	return *old
+}
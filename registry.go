@@ -30,11 +30,32 @@ func NewRegistry() Registry {
 
 type Value struct {
 	*constructor
+	Type reflect.Type
 	Name string
 }
 
 func (v Value) Instance() any {
-	return v.constructor.instance
+	return v.constructor.loadInstance()
+}
+
+// Built reports whether the constructor has been built
+func (v Value) Built() bool {
+	return v.constructor.loadInstance() != nil
+}
+
+// ValidateFlags validates the flags bound to the builder
+func (v Value) ValidateFlags() error {
+	return v.constructor.validateFlags()
+}
+
+// Flags returns the full names and current values of the flags bound to the builder,
+// the values are not redacted, see Registry.Snapshot
+func (v Value) Flags() map[string]string {
+	values := make(map[string]string, len(v.constructor.flags))
+	for name, f := range v.constructor.flags {
+		values[name] = f.Value.String()
+	}
+	return values
 }
 
 func (v Value) Builder() any {
@@ -43,9 +64,10 @@ func (v Value) Builder() any {
 
 // VisitAll Iterate all the built constructors
 func (r Registry) Visit(fn func(v Value)) {
-	for _, c := range r.constructors {
+	for typ, c := range r.constructors {
 		for name, v := range c.groups {
 			fn(Value{
+				Type:        typ,
 				Name:        name,
 				constructor: v,
 			})
@@ -73,22 +95,36 @@ func (r Registry) Provide(typ reflect.Type, flaggerBuilder any, buildFunc func(c
 	} else {
 		r.constructors[typ] = newConstructorGroup()
 	}
-	if provideOptions.flagset != nil {
-		sf.AddFlags(provideOptions.flagset)
+	target := injectTargetOf(flaggerBuilder)
+	injections, err := parseInjections(target)
+	if err != nil {
+		panic(fmt.Errorf("type: %s, Name: %s inject failed: %w", typ, provideOptions.name, err))
 	}
 	c := &constructor{
 		builder:           flaggerBuilder,
+		target:            target,
+		injections:        injections,
 		validateFlagsFunc: sf.ValidateFlags,
 		buildFunc:         buildFunc,
 		selections:        provideOptions.selections,
 		implements:        provideOptions.implements,
 		optionals:         provideOptions.optionals,
 	}
+	if provideOptions.flagset != nil {
+		c.addFlags(sf, provideOptions.flagset)
+	}
 	if err := r.constructors[typ].add(provideOptions.name, c); err != nil {
 		panic(fmt.Errorf("type: %s, Name: %s add failed: %s", typ, provideOptions.name, err))
 	}
 }
 
+// Rebuild builds the constructor of typ and name again with the current flag values,
+// and returns the previous instance and the new instance.
+// The dependencies of the constructor are not rebuilt.
+func (r Registry) Rebuild(ctx context.Context, typ reflect.Type, name string) (old any, rtn any, err error) {
+	return r.container.rebuild(withContext(ctx, newBaseContext(r.container)), typ, name)
+}
+
 func (r Registry) ValidateFlags() error {
 	return r.container.validateFlags()
 }
@@ -0,0 +1,112 @@
+package di
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Snapshot records the state of all constructors of a registry at a moment
+type Snapshot struct {
+	Components []ComponentState
+}
+
+// ComponentState records the state of a single constructor
+type ComponentState struct {
+	Type    string
+	Name    string
+	Builder string
+	Built   bool
+	// Flags are the raw values of the flags bound to the builder, keyed by full flag name.
+	// secret values are NOT redacted, see Registry.Snapshot.
+	Flags map[string]string
+}
+
+func (cs ComponentState) key() string {
+	return cs.Type + "(" + cs.Name + ")"
+}
+
+// FlagDiff records the change of a flag value, Old and New are raw values as in Snapshot
+type FlagDiff struct {
+	Key string
+	Old string
+	New string
+}
+
+// ComponentDiff records the changes of a constructor between two snapshots
+type ComponentDiff struct {
+	Type string
+	Name string
+	// Added and Removed report whether the constructor only exists in the new or old snapshot
+	Added   bool
+	Removed bool
+	// Built reports whether the constructor has been built in the new snapshot
+	Built bool
+	Flags []FlagDiff
+}
+
+func (cd ComponentDiff) String() string {
+	return fmt.Sprintf("%s(%s)", cd.Type, cd.Name)
+}
+
+// Snapshot captures the built state, builder types and bound flag values of all constructors.
+// the components are sorted by type and name.
+//
+// The flag values are captured as they are, so that Diff can detect every change,
+// which means passwords, tokens and other secrets are included in plain text.
+// The registry does not know which flags are secret, a snapshot must not be logged,
+// printed or exposed as is, use box.Snapshot which redacts the secret flags instead.
+func (r Registry) Snapshot() Snapshot {
+	var s Snapshot
+	r.Visit(func(v Value) {
+		s.Components = append(s.Components, ComponentState{
+			Type:    v.Type.String(),
+			Name:    v.Name,
+			Builder: fmt.Sprintf("%T", v.Builder()),
+			Built:   v.Built(),
+			Flags:   v.Flags(),
+		})
+	})
+	sort.Slice(s.Components, func(i, j int) bool {
+		if s.Components[i].Type != s.Components[j].Type {
+			return s.Components[i].Type < s.Components[j].Type
+		}
+		return s.Components[i].Name < s.Components[j].Name
+	})
+	return s
+}
+
+// Diff returns the constructors that are added, removed or have flag changes from a to b.
+// constructors without any change are omitted.
+func Diff(a, b Snapshot) []ComponentDiff {
+	old := make(map[string]ComponentState, len(a.Components))
+	for _, cs := range a.Components {
+		old[cs.key()] = cs
+	}
+	var diffs []ComponentDiff
+	for _, cs := range b.Components {
+		prev, ok := old[cs.key()]
+		delete(old, cs.key())
+		cd := ComponentDiff{Type: cs.Type, Name: cs.Name, Built: cs.Built, Added: !ok}
+		for key, value := range cs.Flags {
+			if prevValue, ok := prev.Flags[key]; !ok || prevValue != value {
+				cd.Flags = append(cd.Flags, FlagDiff{Key: key, Old: prevValue, New: value})
+			}
+		}
+		for key, value := range prev.Flags {
+			if _, ok := cs.Flags[key]; !ok {
+				cd.Flags = append(cd.Flags, FlagDiff{Key: key, Old: value})
+			}
+		}
+		if !cd.Added && len(cd.Flags) == 0 {
+			continue
+		}
+		sort.Slice(cd.Flags, func(i, j int) bool { return cd.Flags[i].Key < cd.Flags[j].Key })
+		diffs = append(diffs, cd)
+	}
+	for _, cs := range a.Components {
+		if _, ok := old[cs.key()]; ok {
+			diffs = append(diffs, ComponentDiff{Type: cs.Type, Name: cs.Name, Built: cs.Built, Removed: true})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,75 @@
+package di
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := Snapshot{Components: []ComponentState{
+		{Type: "*redis.Client", Flags: map[string]string{"redis-addr": "127.0.0.1", "redis-db": "0"}},
+		{Type: "*mysql.Client", Flags: map[string]string{"mysql-addr": "127.0.0.1"}},
+		{Type: "*http.Server", Name: "old"},
+	}}
+	b := Snapshot{Components: []ComponentState{
+		{Type: "*redis.Client", Built: true, Flags: map[string]string{"redis-addr": "10.0.0.1", "redis-db": "0"}},
+		{Type: "*mysql.Client", Flags: map[string]string{"mysql-addr": "127.0.0.1"}},
+		{Type: "*http.Server", Name: "new"},
+	}}
+	want := []ComponentDiff{
+		{Type: "*redis.Client", Built: true, Flags: []FlagDiff{{Key: "redis-addr", Old: "127.0.0.1", New: "10.0.0.1"}}},
+		{Type: "*http.Server", Name: "new", Added: true},
+		{Type: "*http.Server", Name: "old", Removed: true},
+	}
+	if got := Diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+}
+
+type snapshotTestRedis struct {
+	Addr string `flag:"addr" default:"127.0.0.1:6379"`
+	DB   int    `flag:"db" default:"0"`
+}
+
+type snapshotTestMySQL struct {
+	Addr string `flag:"addr" default:"127.0.0.1:3306"`
+}
+
+func TestRegistrySnapshot(t *testing.T) {
+	r := NewRegistry()
+	redis, mysql := &snapshotTestRedis{}, &snapshotTestMySQL{}
+	redisFlags, mysqlFlags := flag.NewFlagSet("redis", flag.ContinueOnError), flag.NewFlagSet("mysql", flag.ContinueOnError)
+	r.Provide(reflectType[*snapshotTestRedis](), redis, func(ctx context.Context) (any, error) {
+		return redis, nil
+	}, WithFlagset(redisFlags))
+	r.Provide(reflectType[*snapshotTestMySQL](), mysql, func(ctx context.Context) (any, error) {
+		return mysql, nil
+	}, WithFlagset(mysqlFlags), WithName("replica"))
+	if err := redisFlags.Set("addr", "10.0.0.1:6379"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := withContext(context.Background(), newBaseContext(r.container))
+	if _, err := r.build(ctx, reflectType[*snapshotTestRedis](), ""); err != nil {
+		t.Fatal(err)
+	}
+	want := Snapshot{Components: []ComponentState{
+		{
+			Type:    "*di.snapshotTestMySQL",
+			Name:    "replica",
+			Builder: "*di.snapshotTestMySQL",
+			Flags:   map[string]string{"mysql-addr": "127.0.0.1:3306"},
+		},
+		{
+			Type:    "*di.snapshotTestRedis",
+			Builder: "*di.snapshotTestRedis",
+			Built:   true,
+			Flags:   map[string]string{"redis-addr": "10.0.0.1:6379", "redis-db": "0"},
+		},
+	}}
+	if got := r.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+}